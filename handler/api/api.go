@@ -23,6 +23,7 @@ import (
 	"github.com/micro-community/micro-webui/handler"
 	"github.com/micro-community/micro-webui/helper/ctx"
 	"github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/transform"
 	api "github.com/micro/micro/v3/proto/api"
 	goapi "github.com/micro/micro/v3/service/api"
 	"github.com/micro/micro/v3/service/client"
@@ -40,21 +41,6 @@ const (
 
 // API handler is the default handler which takes api.Request and returns api.Response
 func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	bsize := handler.DefaultMaxRecvSize
-	if a.opts.MaxRecvSize > 0 {
-		bsize = a.opts.MaxRecvSize
-	}
-
-	r.Body = http.MaxBytesReader(w, r.Body, bsize)
-	request, err := requestToProto(r)
-	if err != nil {
-		er := errors.InternalServerError("go.micro.api", err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(500)
-		w.Write([]byte(er.Error()))
-		return
-	}
-
 	var service *goapi.Service
 
 	if a.s != nil {
@@ -62,7 +48,7 @@ func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		service = a.s
 	} else if a.opts.Router != nil {
 		// try get service from router
-		s, err := a.opts.Router.Route(r)
+		s, rr, err := a.opts.Router.Route(r)
 		if err != nil {
 			er := errors.InternalServerError("go.micro.api", err.Error())
 			w.Header().Set("Content-Type", "application/json")
@@ -71,6 +57,7 @@ func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		service = s
+		r = rr
 	} else {
 		// we have no way of routing the request
 		er := errors.InternalServerError("go.micro.api", "no route found")
@@ -80,6 +67,38 @@ func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// apply the route transform rules
+	w, r = transform.FromContext(r.Context()).Apply(w, r)
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.serve(w, r, service)
+	})
+
+	// serve cacheable requests from the cache
+	if a.opts.Cache != nil {
+		h = a.opts.Cache.Handler(service, h)
+	}
+
+	h.ServeHTTP(w, r)
+}
+
+// serve calls the routed service with the request
+func (a *apiHandler) serve(w http.ResponseWriter, r *http.Request, service *goapi.Service) {
+	bsize := handler.DefaultMaxRecvSize
+	if a.opts.MaxRecvSize > 0 {
+		bsize = a.opts.MaxRecvSize
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, bsize)
+	request, err := requestToProto(r)
+	if err != nil {
+		er := errors.InternalServerError("go.micro.api", err.Error())
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(500)
+		w.Write([]byte(er.Error()))
+		return
+	}
+
 	// create request and response
 	c := a.opts.Client
 	req := c.NewRequest(service.Name, service.Endpoint.Name, request)
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/server/cors"
+	"github.com/micro/micro/v3/service/errors"
+)
+
+var (
+	// DefaultBatchConcurrency is the most calls of a batch made at once
+	DefaultBatchConcurrency = 8
+	// MaxBatchConcurrency caps the concurrency a batch can ask for
+	MaxBatchConcurrency = 64
+	// MaxBatchSize is the most calls in a batch
+	MaxBatchSize = 100
+)
+
+type batchRequest struct {
+	Requests []*rpcRequest `json:"requests"`
+	// Timeout of the whole batch as a duration e.g 10s or a number of seconds,
+	// calls with a timeout of their own are also held to it
+	Timeout interface{} `json:"timeout"`
+	// Concurrency is the most calls made at once
+	Concurrency int `json:"concurrency"`
+	// StopOnError skips the calls not yet made once one fails
+	StopOnError bool `json:"stop_on_error"`
+}
+
+type batchResult struct {
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    *errors.Error   `json:"error,omitempty"`
+	// Skipped is set for calls not made after a failure
+	Skipped bool   `json:"skipped,omitempty"`
+	Node    string `json:"node,omitempty"`
+	// Latency in milliseconds
+	Latency float64 `json:"latency"`
+}
+
+type batchHandler struct {
+	rpc *rpcHandler
+}
+
+func (h *batchHandler) String() string {
+	return "internal/batch"
+}
+
+// ServeHTTP makes the JSON or form encoded RPC requests of a batch concurrently,
+// returning their results in order
+func (h *batchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		cors.SetHeaders(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	defer r.Body.Close()
+
+	badRequest := func(description string) {
+		e := errors.BadRequest("micro.rpc", description)
+		w.WriteHeader(400)
+		w.Write([]byte(e.Error()))
+	}
+
+	// response content type
+	w.Header().Set("Content-Type", "application/json")
+
+	ct := r.Header.Get("Content-Type")
+
+	// Strip charset from Content-Type (like `application/json; charset=UTF-8`)
+	if idx := strings.IndexRune(ct, ';'); idx >= 0 {
+		ct = ct[:idx]
+	}
+
+	var batch batchRequest
+
+	switch ct {
+	case "application/json":
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			badRequest(err.Error())
+			return
+		}
+
+		// either the requests alone or with the batch options
+		var v interface{} = &batch
+		if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+			v = &batch.Requests
+		}
+
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+
+		if err := d.Decode(v); err != nil {
+			badRequest(err.Error())
+			return
+		}
+	default:
+		r.ParseForm()
+
+		d := json.NewDecoder(strings.NewReader(r.Form.Get("requests")))
+		d.UseNumber()
+
+		if err := d.Decode(&batch.Requests); err != nil {
+			badRequest("error decoding requests: " + err.Error())
+			return
+		}
+
+		if t := r.Form.Get("timeout"); len(t) > 0 {
+			batch.Timeout = t
+		}
+		batch.Concurrency, _ = strconv.Atoi(r.Form.Get("concurrency"))
+		batch.StopOnError, _ = strconv.ParseBool(r.Form.Get("stop_on_error"))
+	}
+
+	if len(batch.Requests) == 0 {
+		badRequest("no requests")
+		return
+	}
+
+	if len(batch.Requests) > MaxBatchSize {
+		badRequest("too many requests, the most is " + strconv.Itoa(MaxBatchSize))
+		return
+	}
+
+	timeout, err := ParseTimeout(batch.Timeout)
+	if err != nil {
+		badRequest("invalid timeout: " + err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// the deadline is shared by every call
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	results := h.call(r.WithContext(ctx), &batch)
+
+	w.Header().Set(LatencyHeader, strconv.FormatFloat(float64(time.Since(start))/float64(time.Millisecond), 'f', 3, 64))
+
+	b, err := json.Marshal(map[string]interface{}{
+		"results": results,
+	})
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(errors.InternalServerError("micro.rpc", err.Error()).Error()))
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.Write(b)
+}
+
+// call makes the requests with a pool of workers, skipping those not yet
+// made on failure if the batch stops on errors
+func (h *batchHandler) call(r *http.Request, batch *batchRequest) []*batchResult {
+	results := make([]*batchResult, len(batch.Requests))
+
+	concurrency := batch.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	if concurrency > MaxBatchConcurrency {
+		concurrency = MaxBatchConcurrency
+	}
+	if concurrency > len(batch.Requests) {
+		concurrency = len(batch.Requests)
+	}
+
+	jobs := make(chan int, len(batch.Requests))
+	for i := range batch.Requests {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	var failed bool
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				mtx.Lock()
+				skip := failed && batch.StopOnError
+				mtx.Unlock()
+
+				if skip {
+					results[i] = &batchResult{Skipped: true}
+					continue
+				}
+
+				res := h.callOne(r, batch.Requests[i])
+				results[i] = res
+
+				// calls in flight are left to finish
+				if res.Error != nil {
+					mtx.Lock()
+					failed = true
+					mtx.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+func (h *batchHandler) callOne(r *http.Request, req *rpcRequest) *batchResult {
+	if req == nil {
+		return &batchResult{Error: errors.BadRequest("micro.rpc", "invalid request").(*errors.Error)}
+	}
+
+	timeout, err := req.validate()
+	if err != nil {
+		return &batchResult{Error: errors.BadRequest("micro.rpc", err.Error()).(*errors.Error)}
+	}
+
+	// each call gets its own deadline, the earlier of its timeout and the
+	// batch deadline, as the client takes its timeout from the context
+	if deadline, ok := r.Context().Deadline(); ok {
+		if left := time.Until(deadline); timeout == 0 || left < timeout {
+			timeout = left
+		}
+	}
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	start := time.Now()
+	response, node, err := h.rpc.call(r, req, timeout)
+
+	res := &batchResult{
+		Node:    node,
+		Latency: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		ce := errors.Parse(err.Error())
+		if ce.Code == 0 {
+			// assuming it's totally screwed
+			ce.Code = 500
+			ce.Id = "micro.rpc"
+			ce.Status = http.StatusText(500)
+			ce.Detail = "error during request: " + ce.Detail
+		}
+		res.Error = ce
+		return res
+	}
+
+	res.Response = response
+	return res
+}
+
+// NewBatchHandler returns a handler making many RPC requests at once
+func NewBatchHandler(r resolver.Resolver) Handler {
+	return &batchHandler{&rpcHandler{r}}
+}
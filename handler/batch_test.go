@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micro/micro/v3/profile"
+	"github.com/micro/micro/v3/service"
+	"github.com/micro/micro/v3/service/errors"
+)
+
+type BatchHandler struct{}
+
+type BatchRequest struct {
+	Name string `json:"name"`
+}
+
+type BatchResponse struct {
+	Msg string `json:"msg"`
+}
+
+func (b *BatchHandler) Exec(ctx context.Context, req *BatchRequest, rsp *BatchResponse) error {
+	rsp.Msg = "Hello " + req.Name
+	return nil
+}
+
+func (b *BatchHandler) Fail(ctx context.Context, req *BatchRequest, rsp *BatchResponse) error {
+	return errors.Forbidden("test.batch", "not allowed")
+}
+
+func (b *BatchHandler) Slow(ctx context.Context, req *BatchRequest, rsp *BatchResponse) error {
+	select {
+	case <-time.After(time.Second * 2):
+	case <-ctx.Done():
+	}
+	rsp.Msg = "Hello " + req.Name
+	return nil
+}
+
+func (b *BatchHandler) Pause(ctx context.Context, req *BatchRequest, rsp *BatchResponse) error {
+	select {
+	case <-time.After(time.Millisecond * 100):
+	case <-ctx.Done():
+		return errors.Timeout("test.batch", "cancelled")
+	}
+	rsp.Msg = "Hello " + req.Name
+	return nil
+}
+
+func TestBatchHandler(t *testing.T) {
+	profile.Test.Setup(nil)
+
+	srv := service.New(
+		service.Name("test.batch"),
+	)
+
+	srv.Server().Handle(
+		srv.Server().NewHandler(&BatchHandler{}),
+	)
+
+	if err := srv.Server().Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer srv.Server().Stop()
+
+	call := func(ct, body string) []*batchResult {
+		req, err := http.NewRequest("POST", "/rpc/batch", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", ct)
+
+		w := httptest.NewRecorder()
+		NewBatchHandler(nil).ServeHTTP(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("Expected 200 response got %d %s", w.Code, w.Body.String())
+		}
+
+		var rsp struct {
+			Results []*batchResult `json:"results"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+		return rsp.Results
+	}
+
+	// results are in the order of the requests
+	results := call("application/json", `[
+		{"service": "test.batch", "endpoint": "BatchHandler.Exec", "request": {"name": "a"}},
+		{"service": "test.batch"},
+		{"service": "test.batch", "endpoint": "BatchHandler.Fail", "request": {}},
+		{"service": "test.batch", "endpoint": "BatchHandler.Exec", "request": "{\"name\": \"b\"}"}
+	]`)
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results got %d", len(results))
+	}
+	if string(results[0].Response) != `{"msg":"Hello a"}` || results[0].Error != nil {
+		t.Fatalf("Expected a response got %s %v", results[0].Response, results[0].Error)
+	}
+	if results[1].Error == nil || results[1].Error.Code != 400 {
+		t.Fatalf("Expected a bad request got %v", results[1].Error)
+	}
+	if results[2].Error == nil || results[2].Error.Code != 403 {
+		t.Fatalf("Expected the call error got %v", results[2].Error)
+	}
+	if string(results[3].Response) != `{"msg":"Hello b"}` || results[3].Skipped {
+		t.Fatalf("Expected a response got %s %v", results[3].Response, results[3].Error)
+	}
+
+	// calls after a failure are skipped
+	results = call("application/json", `{
+		"requests": [
+			{"service": "test.batch", "endpoint": "BatchHandler.Fail", "request": {}},
+			{"service": "test.batch", "endpoint": "BatchHandler.Exec", "request": {"name": "a"}}
+		],
+		"concurrency": 1,
+		"stop_on_error": true,
+		"timeout": "5s"
+	}`)
+
+	if len(results) != 2 || results[0].Error == nil || !results[1].Skipped {
+		t.Fatalf("Expected the second call to be skipped got %+v", results)
+	}
+
+	// calls in flight aren't stopped by a failure
+	results = call("application/json", `{
+		"requests": [
+			{"service": "test.batch", "endpoint": "BatchHandler.Pause", "request": {"name": "a"}},
+			{"service": "test.batch", "endpoint": "BatchHandler.Fail", "request": {}},
+			{"service": "test.batch", "endpoint": "BatchHandler.Exec", "request": {"name": "b"}}
+		],
+		"concurrency": 2,
+		"stop_on_error": true
+	}`)
+
+	if len(results) != 3 || string(results[0].Response) != `{"msg":"Hello a"}` || results[0].Error != nil {
+		t.Fatalf("Expected the call in flight to finish got %+v", results[0])
+	}
+	if results[1].Error == nil || !results[2].Skipped {
+		t.Fatalf("Expected the call after the failure to be skipped got %+v %+v", results[1], results[2])
+	}
+
+	// a call's own timeout applies within the batch timeout
+	results = call("application/json", `{
+		"requests": [
+			{"service": "test.batch", "endpoint": "BatchHandler.Slow", "request": {"name": "a"}, "timeout": "100ms"},
+			{"service": "test.batch", "endpoint": "BatchHandler.Exec", "request": {"name": "b"}}
+		],
+		"timeout": "10s"
+	}`)
+
+	if len(results) != 2 || results[0].Error == nil || results[0].Error.Code != 408 {
+		t.Fatalf("Expected the slow call to time out got %+v", results)
+	}
+	if results[0].Latency > 1000 {
+		t.Fatalf("Expected the slow call to time out after its own timeout got %vms", results[0].Latency)
+	}
+	if string(results[1].Response) != `{"msg":"Hello b"}` {
+		t.Fatalf("Expected a response got %s %v", results[1].Response, results[1].Error)
+	}
+
+	form := url.Values{
+		"requests":      {`[{"service": "test.batch", "endpoint": "BatchHandler.Exec", "request": {"name": "a"}}]`},
+		"stop_on_error": {"true"},
+	}
+	results = call("application/x-www-form-urlencoded", form.Encode())
+
+	if len(results) != 1 || string(results[0].Response) != `{"msg":"Hello a"}` {
+		t.Fatalf("Expected a response got %+v", results)
+	}
+}
+
+func TestBatchHandlerInvalid(t *testing.T) {
+	testData := []string{
+		`[]`,
+		`{"requests": [{"service": "test.batch", "endpoint": "BatchHandler.Exec"}], "timeout": "soon"}`,
+		`{"requests": `,
+	}
+
+	for _, d := range testData {
+		req, err := http.NewRequest("POST", "/rpc/batch", strings.NewReader(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		NewBatchHandler(nil).ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Fatalf("Expected 400 response for %s got %d", d, w.Code)
+		}
+	}
+}
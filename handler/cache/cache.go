@@ -0,0 +1,506 @@
+// Package cache is a http response cache for GET api routes
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/auth"
+	"github.com/micro/micro/v3/service/logger"
+	"github.com/micro/micro/v3/service/store"
+)
+
+const (
+	// TTLKey is the endpoint metadata key used to set a cache ttl e.g 30s
+	TTLKey = "cache_ttl"
+	// StatusHeader reports whether a response was served from cache
+	StatusHeader = "X-Micro-Cache"
+)
+
+// entry is a cached response
+type entry struct {
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Vary    []string    `json:"vary,omitempty"`
+	Created time.Time   `json:"created"`
+	Expires time.Time   `json:"expires"`
+}
+
+func (e *entry) expired() bool {
+	return time.Now().After(e.Expires)
+}
+
+// Cache is a two tier response cache, memory then store
+type Cache struct {
+	opts Options
+	lru  *lru
+
+	sync.Mutex
+	// in flight misses used to coalesce requests
+	calls map[string]chan struct{}
+}
+
+// New returns a new response cache
+func New(opts ...Option) *Cache {
+	options := NewOptions(opts...)
+
+	return &Cache{
+		opts:  options,
+		lru:   newLRU(options.Size),
+		calls: make(map[string]chan struct{}),
+	}
+}
+
+// Handler returns next wrapped with the cache for the routed service
+func (c *Cache) Handler(svc *api.Service, next http.Handler) http.Handler {
+	ttl := endpointTTL(svc)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || upgrade(r) || bypass(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		base := key(r)
+
+		if e, ok := c.lookup(base, r); ok {
+			c.serve(w, r, e)
+			return
+		}
+
+		// coalesce concurrent misses so only one request goes upstream
+		if wait, leader := c.join(base); !leader {
+			select {
+			case <-wait:
+			case <-r.Context().Done():
+				return
+			}
+
+			if e, ok := c.lookup(base, r); ok {
+				c.serve(w, r, e)
+				return
+			}
+
+			// the leader's response wasn't cacheable
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer c.leave(base)
+
+		rec := &recorder{ResponseWriter: w, max: c.opts.MaxEntrySize}
+		rec.Header().Set(StatusHeader, "MISS")
+		next.ServeHTTP(rec, r)
+
+		c.save(base, r, rec, ttl)
+	})
+}
+
+// Purge removes the entries of the host with an escaped request path
+// and query matching the prefix
+func (c *Cache) Purge(host, prefix string) (int, error) {
+	prefix = host + " " + prefix
+	n := c.lru.purge(prefix)
+
+	st := c.store()
+	if st == nil {
+		return n, nil
+	}
+
+	keys, err := st.List(store.ListPrefix(c.opts.Prefix + prefix))
+	if err != nil {
+		return n, err
+	}
+
+	for _, k := range keys {
+		if err := st.Delete(k); err != nil {
+			return n, err
+		}
+	}
+
+	if len(keys) > n {
+		n = len(keys)
+	}
+
+	return n, nil
+}
+
+// PurgeHandler serves the purge api, the prefix is passed as a form value.
+// Callers need an account and only purge the entries of the host they call.
+func (c *Cache) PurgeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" && r.Method != "DELETE" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, ok := auth.AccountFromContext(r.Context()); !ok {
+			http.Error(w, "An account is required", http.StatusUnauthorized)
+			return
+		}
+
+		r.ParseForm()
+
+		n, err := c.Purge(r.Host, r.Form.Get("prefix"))
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+
+		b, _ := json.Marshal(map[string]interface{}{
+			"purged": n,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
+
+func (c *Cache) store() store.Store {
+	if c.opts.DisableStore {
+		return nil
+	}
+	if c.opts.Store != nil {
+		return c.opts.Store
+	}
+	return store.DefaultStore
+}
+
+// join returns true if the caller should make the upstream request
+func (c *Cache) join(key string) (chan struct{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if ch, ok := c.calls[key]; ok {
+		return ch, false
+	}
+
+	c.calls[key] = make(chan struct{})
+	return nil, true
+}
+
+func (c *Cache) leave(key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if ch, ok := c.calls[key]; ok {
+		close(ch)
+		delete(c.calls, key)
+	}
+}
+
+// lookup finds the entry for the request, resolving any Vary variants
+func (c *Cache) lookup(base string, r *http.Request) (*entry, bool) {
+	e, ok := c.get(base)
+	if !ok {
+		return nil, false
+	}
+
+	if len(e.Vary) > 0 {
+		return c.get(variant(base, e.Vary, r))
+	}
+
+	return e, true
+}
+
+func (c *Cache) get(key string) (*entry, bool) {
+	if e, ok := c.lru.get(key); ok {
+		if !e.expired() {
+			return e, true
+		}
+		c.lru.del(key)
+		return nil, false
+	}
+
+	st := c.store()
+	if st == nil {
+		return nil, false
+	}
+
+	recs, err := st.Read(c.opts.Prefix + key)
+	if err != nil || len(recs) == 0 {
+		return nil, false
+	}
+
+	e := new(entry)
+	if err := json.Unmarshal(recs[0].Value, e); err != nil || e.expired() {
+		return nil, false
+	}
+
+	// promote to memory
+	c.lru.set(key, e)
+
+	return e, true
+}
+
+func (c *Cache) set(key string, e *entry) {
+	c.lru.set(key, e)
+
+	st := c.store()
+	if st == nil {
+		return
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	if err := st.Write(&store.Record{
+		Key:    c.opts.Prefix + key,
+		Value:  b,
+		Expiry: time.Until(e.Expires),
+	}); err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("unable to write cache entry: %v", err)
+		}
+	}
+}
+
+// save stores the recorded response if it's cacheable
+func (c *Cache) save(base string, r *http.Request, rec *recorder, ttl time.Duration) {
+	if rec.status != http.StatusOK || rec.overflow {
+		return
+	}
+
+	h := rec.Header()
+
+	// never share cookies
+	if len(h.Get("Set-Cookie")) > 0 {
+		return
+	}
+
+	cc := parseCacheControl(h.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return
+	}
+	if _, ok := cc["private"]; ok {
+		return
+	}
+
+	// authorized responses, by header or a session cookie, are only shared when marked public
+	if _, ok := cc["public"]; !ok && (len(r.Header.Get("Authorization")) > 0 || len(r.Header.Get("Cookie")) > 0) {
+		return
+	}
+
+	// explicit upstream directives win over endpoint metadata
+	if v, ok := cc["s-maxage"]; ok {
+		ttl = seconds(v)
+	} else if v, ok := cc["max-age"]; ok {
+		ttl = seconds(v)
+	} else if ttl == 0 {
+		ttl = c.opts.DefaultTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	var vary []string
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); len(f) > 0 {
+				vary = append(vary, http.CanonicalHeaderKey(f))
+			}
+		}
+	}
+
+	for _, v := range vary {
+		if v == "*" {
+			return
+		}
+	}
+
+	header := h.Clone()
+	header.Del(StatusHeader)
+	if len(header.Get("ETag")) == 0 {
+		sum := fnv.New64a()
+		sum.Write(rec.body)
+		header.Set("ETag", fmt.Sprintf(`W/"%x"`, sum.Sum64()))
+	}
+
+	now := time.Now()
+	e := &entry{
+		Status:  rec.status,
+		Header:  header,
+		Body:    rec.body,
+		Created: now,
+		Expires: now.Add(ttl),
+	}
+
+	if len(vary) == 0 {
+		c.set(base, e)
+		return
+	}
+
+	sort.Strings(vary)
+
+	// the base key records how to find the variant
+	c.set(base, &entry{Vary: vary, Created: now, Expires: e.Expires})
+	c.set(variant(base, vary, r), e)
+}
+
+// serve writes a cached entry, answering conditional requests
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request, e *entry) {
+	for k, v := range e.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(StatusHeader, "HIT")
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(e.Created).Seconds())))
+
+	if inm := r.Header.Get("If-None-Match"); len(inm) > 0 && etagMatch(inm, e.Header.Get("ETag")) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(e.Body)))
+	w.WriteHeader(e.Status)
+	w.Write(e.Body)
+}
+
+// recorder tees the response to the client while keeping a copy
+type recorder struct {
+	http.ResponseWriter
+	max      int
+	status   int
+	body     []byte
+	overflow bool
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if !r.overflow {
+		if len(r.body)+len(b) > r.max {
+			r.overflow = true
+			r.body = nil
+		} else {
+			r.body = append(r.body, b...)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// key is the host followed by the escaped path and query
+func key(r *http.Request) string {
+	return r.Host + " " + r.URL.EscapedPath() + "?" + r.URL.RawQuery
+}
+
+func variant(base string, vary []string, r *http.Request) string {
+	parts := []string{base}
+	for _, v := range vary {
+		parts = append(parts, v+"="+r.Header.Get(v))
+	}
+	return strings.Join(parts, "|")
+}
+
+// upgrade reports whether the request asks to upgrade the connection e.g
+// to a websocket, which hijacks the connection from under the recorder
+func upgrade(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// bypass checks the request cache directives
+func bypass(r *http.Request) bool {
+	cc := parseCacheControl(r.Header.Get("Cache-Control"))
+	if _, ok := cc["no-cache"]; ok {
+		return true
+	}
+	if _, ok := cc["no-store"]; ok {
+		return true
+	}
+	return r.Header.Get("Pragma") == "no-cache"
+}
+
+func parseCacheControl(v string) map[string]string {
+	cc := make(map[string]string)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		if idx := strings.IndexRune(part, '='); idx >= 0 {
+			cc[strings.ToLower(part[:idx])] = strings.Trim(part[idx+1:], `"`)
+			continue
+		}
+		cc[strings.ToLower(part)] = ""
+	}
+	return cc
+}
+
+func seconds(v string) time.Duration {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+func etagMatch(inm, etag string) bool {
+	if len(etag) == 0 {
+		return false
+	}
+	for _, t := range strings.Split(inm, ",") {
+		t = strings.TrimSpace(t)
+		// weak comparison per RFC 7232
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointTTL reads the cache ttl from the routed endpoint's metadata
+func endpointTTL(svc *api.Service) time.Duration {
+	if svc == nil || svc.Endpoint == nil {
+		return 0
+	}
+
+	for _, s := range svc.Services {
+		for _, ep := range s.Endpoints {
+			if ep.Name != svc.Endpoint.Name {
+				continue
+			}
+			v, ok := ep.Metadata[TTLKey]
+			if !ok {
+				continue
+			}
+			if d, err := time.ParseDuration(v); err == nil {
+				return d
+			}
+			return seconds(v)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,343 @@
+package cache
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/auth"
+	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/store/memory"
+)
+
+func get(h http.Handler, path string, header map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", path, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func counter(cc string, calls *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if len(cc) > 0 {
+			w.Header().Set("Cache-Control", cc)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"msg":"hello"}`))
+	})
+}
+
+func TestCacheControl(t *testing.T) {
+	testData := []struct {
+		name   string
+		cc     string
+		ttl    string
+		calls  int32
+		status string
+	}{
+		{"max-age", "max-age=60", "", 1, "HIT"},
+		{"s-maxage", "public, s-maxage=60", "", 1, "HIT"},
+		{"no-store", "no-store", "30s", 2, "MISS"},
+		{"private", "private, max-age=60", "", 2, "MISS"},
+		{"metadata ttl", "", "30s", 1, "HIT"},
+		{"metadata seconds", "", "30", 1, "HIT"},
+		{"nothing", "", "", 2, "MISS"},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			var calls int32
+
+			svc := &api.Service{
+				Name:     "go.micro.api.test",
+				Endpoint: &api.Endpoint{Name: "Test.Call"},
+				Services: []*registry.Service{{
+					Name: "go.micro.api.test",
+					Endpoints: []*registry.Endpoint{{
+						Name:     "Test.Call",
+						Metadata: map[string]string{},
+					}},
+				}},
+			}
+			if len(d.ttl) > 0 {
+				svc.Services[0].Endpoints[0].Metadata[TTLKey] = d.ttl
+			}
+
+			h := New(DisableStore()).Handler(svc, counter(d.cc, &calls))
+
+			get(h, "/test/call", nil)
+			w := get(h, "/test/call", nil)
+
+			if calls != d.calls {
+				t.Fatalf("Expected %d upstream calls got %d", d.calls, calls)
+			}
+			if s := w.Header().Get(StatusHeader); s != d.status {
+				t.Fatalf("Expected cache status %s got %s", d.status, s)
+			}
+			if w.Body.String() != `{"msg":"hello"}` {
+				t.Fatalf("Unexpected body %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCookie(t *testing.T) {
+	testData := []struct {
+		name  string
+		cc    string
+		calls int32
+	}{
+		{"personalized", "max-age=60", 2},
+		{"public", "public, max-age=60", 1},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			var calls int32
+
+			h := New(DisableStore()).Handler(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				c, _ := r.Cookie("micro-token")
+				w.Header().Set("Cache-Control", d.cc)
+				w.Write([]byte("hello " + c.Value))
+			}))
+
+			get(h, "/test/call", map[string]string{"Cookie": "micro-token=alice"})
+			w := get(h, "/test/call", map[string]string{"Cookie": "micro-token=bob"})
+
+			if calls != d.calls {
+				t.Fatalf("Expected %d upstream calls got %d", d.calls, calls)
+			}
+			if d.calls == 2 && w.Body.String() != "hello bob" {
+				t.Fatalf("Expected the response of bob got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestConditional(t *testing.T) {
+	var calls int32
+
+	h := New(DisableStore()).Handler(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{}`))
+	}))
+
+	get(h, "/foo", nil)
+
+	w := get(h, "/foo", map[string]string{"If-None-Match": `W/"v1"`})
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 got %d", w.Code)
+	}
+	if w.Body.Len() > 0 {
+		t.Fatalf("Expected empty body got %s", w.Body.String())
+	}
+
+	w = get(h, "/foo", map[string]string{"If-None-Match": `"v2"`})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 got %d", w.Code)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 upstream call got %d", calls)
+	}
+}
+
+func TestVary(t *testing.T) {
+	var calls int32
+
+	h := New(DisableStore()).Handler(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+
+	for _, lang := range []string{"en", "fr", "en", "fr"} {
+		w := get(h, "/foo", map[string]string{"Accept-Language": lang})
+		if w.Body.String() != lang {
+			t.Fatalf("Expected %s got %s", lang, w.Body.String())
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("Expected 2 upstream calls got %d", calls)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+
+	h := New(DisableStore()).Handler(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(`{}`))
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := get(h, "/foo", nil); w.Body.String() != `{}` {
+				t.Errorf("Unexpected body %s", w.Body.String())
+			}
+		}()
+	}
+
+	// give the requests time to queue up behind the first
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 upstream call got %d", calls)
+	}
+}
+
+func TestStoreAndPurge(t *testing.T) {
+	var calls int32
+	st := memory.NewStore()
+
+	h := counter("max-age=60", &calls)
+
+	New(WithStore(st)).Handler(nil, h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo/bar", nil))
+
+	// a new cache with an empty lru reads through to the store
+	c := New(WithStore(st))
+	if w := get(c.Handler(nil, h), "/foo/bar", nil); w.Header().Get(StatusHeader) != "HIT" {
+		t.Fatalf("Expected store hit got %s", w.Header().Get(StatusHeader))
+	}
+
+	n, err := c.Purge("example.com", "/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("Expected 1 purged entry got %d", n)
+	}
+
+	if w := get(c.Handler(nil, h), "/foo/bar", nil); w.Header().Get(StatusHeader) != "MISS" {
+		t.Fatalf("Expected miss after purge got %s", w.Header().Get(StatusHeader))
+	}
+
+	if calls != 2 {
+		t.Fatalf("Expected 2 upstream calls got %d", calls)
+	}
+}
+
+func TestPurgeHandler(t *testing.T) {
+	var calls int32
+
+	c := New(DisableStore())
+	h := c.Handler(nil, counter("max-age=60", &calls))
+
+	for _, host := range []string{"foo.example.com", "bar.example.com"} {
+		r := httptest.NewRequest("GET", "/foo", nil)
+		r.Host = host
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	purge := func(host string, acc *auth.Account) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/cache/purge", nil)
+		r.Host = host
+		if acc != nil {
+			r = r.WithContext(auth.ContextWithAccount(r.Context(), acc))
+		}
+		w := httptest.NewRecorder()
+		c.PurgeHandler().ServeHTTP(w, r)
+		return w
+	}
+
+	if w := purge("foo.example.com", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without an account got %d", w.Code)
+	}
+
+	// an empty prefix only purges the host called
+	if w := purge("foo.example.com", &auth.Account{ID: "foo"}); w.Code != http.StatusOK || w.Body.String() != `{"purged":1}` {
+		t.Fatalf("Expected 1 purged entry got %d %s", w.Code, w.Body.String())
+	}
+
+	r := httptest.NewRequest("GET", "/foo", nil)
+	r.Host = "bar.example.com"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Header().Get(StatusHeader) != "HIT" {
+		t.Fatalf("Expected the other host to be cached got %s", w.Header().Get(StatusHeader))
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	// echoes whatever is sent once upgraded
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		buf.Flush()
+		io.Copy(conn, buf)
+	}))
+	defer backend.Close()
+
+	u, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frontend := httptest.NewServer(New(DisableStore()).Handler(nil, httputil.NewSingleHostReverseProxy(u)))
+	defer frontend.Close()
+
+	conn, err := net.Dial("tcp", frontend.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := httptest.NewRequest("GET", frontend.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	rsp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 response got %d", rsp.StatusCode)
+	}
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(br, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "ping" {
+		t.Fatalf("Expected ping got %s", b)
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// lru is a size bounded least recently used set of entries
+type lru struct {
+	sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type item struct {
+	key   string
+	entry *entry
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (l *lru) get(key string) (*entry, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return el.Value.(*item).entry, true
+}
+
+func (l *lru) set(key string, e *entry) {
+	l.Lock()
+	defer l.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		el.Value.(*item).entry = e
+		l.ll.MoveToFront(el)
+		return
+	}
+
+	l.items[key] = l.ll.PushFront(&item{key, e})
+
+	for l.size > 0 && l.ll.Len() > l.size {
+		el := l.ll.Back()
+		l.ll.Remove(el)
+		delete(l.items, el.Value.(*item).key)
+	}
+}
+
+func (l *lru) del(key string) {
+	l.Lock()
+	defer l.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		l.ll.Remove(el)
+		delete(l.items, key)
+	}
+}
+
+// purge removes all keys with the given prefix and returns how many were removed
+func (l *lru) purge(prefix string) int {
+	l.Lock()
+	defer l.Unlock()
+
+	var n int
+	for key, el := range l.items {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		l.ll.Remove(el)
+		delete(l.items, key)
+		n++
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/micro/micro/v3/service/store"
+)
+
+var (
+	// DefaultSize is the number of entries held in memory
+	DefaultSize = 1024
+	// DefaultMaxEntrySize is the largest response body which is cached
+	DefaultMaxEntrySize = 1024 * 1024
+	// DefaultPrefix for keys written to the store
+	DefaultPrefix = "web/cache/"
+)
+
+// Options for the response cache
+type Options struct {
+	// Size is the max number of entries in the in-memory LRU
+	Size int
+	// MaxEntrySize is the largest body we'll cache
+	MaxEntrySize int
+	// DefaultTTL used when neither the response nor endpoint set one
+	DefaultTTL time.Duration
+	// Store is the second tier, store.DefaultStore is used if nil
+	Store store.Store
+	// DisableStore only caches in memory
+	DisableStore bool
+	// Prefix for store keys
+	Prefix string
+}
+
+type Option func(o *Options)
+
+// NewOptions returns initialized options
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Size:         DefaultSize,
+		MaxEntrySize: DefaultMaxEntrySize,
+		Prefix:       DefaultPrefix,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+// Size sets the number of entries held in memory
+func Size(n int) Option {
+	return func(o *Options) {
+		o.Size = n
+	}
+}
+
+// MaxEntrySize sets the largest response body which is cached
+func MaxEntrySize(n int) Option {
+	return func(o *Options) {
+		o.MaxEntrySize = n
+	}
+}
+
+// DefaultTTL sets the ttl used when the response and endpoint have none
+func DefaultTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.DefaultTTL = d
+	}
+}
+
+// WithStore sets the store used as the second cache tier
+func WithStore(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// DisableStore only caches responses in memory
+func DisableStore() Option {
+	return func(o *Options) {
+		o.DisableStore = true
+	}
+}
+
+// Prefix sets the prefix for keys written to the store
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
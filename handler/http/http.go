@@ -26,6 +26,7 @@ import (
 	"net/url"
 
 	"github.com/micro-community/micro-webui/handler"
+	"github.com/micro-community/micro-webui/router/transform"
 	"github.com/micro/micro/v3/service/api"
 	"github.com/micro/micro/v3/service/registry"
 )
@@ -42,18 +43,41 @@ type httpHandler struct {
 }
 
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	service, err := h.getService(r)
+	service, r, err := h.getService(r)
 	if err != nil {
 		w.WriteHeader(500)
 		return
 	}
 
-	if len(service) == 0 {
+	// apply the route transform rules
+	w, r = transform.FromContext(r.Context()).Apply(w, r)
+
+	var hd http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, service)
+	})
+
+	// serve cacheable requests from the cache
+	if h.options.Cache != nil {
+		hd = h.options.Cache.Handler(service, hd)
+	}
+
+	hd.ServeHTTP(w, r)
+}
+
+// serve proxies the request to a node of the routed service
+func (h *httpHandler) serve(w http.ResponseWriter, r *http.Request, service *api.Service) {
+	address, err := h.getAddress(service)
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+
+	if len(address) == 0 {
 		w.WriteHeader(404)
 		return
 	}
 
-	rp, err := url.Parse(service)
+	rp, err := url.Parse(address)
 	if err != nil {
 		w.WriteHeader(500)
 		return
@@ -62,25 +86,23 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	httputil.NewSingleHostReverseProxy(rp).ServeHTTP(w, r)
 }
 
-// getService returns the service for this request from the selector
-func (h *httpHandler) getService(r *http.Request) (string, error) {
-	var service *api.Service
-
+// getService returns the service for this request from the router and the
+// request annotated with what the route matched
+func (h *httpHandler) getService(r *http.Request) (*api.Service, *http.Request, error) {
 	if h.s != nil {
 		// we were given the service
-		service = h.s
+		return h.s, r, nil
 	} else if h.options.Router != nil {
 		// try get service from router
-		s, err := h.options.Router.Route(r)
-		if err != nil {
-			return "", err
-		}
-		service = s
-	} else {
-		// we have no way of routing the request
-		return "", errors.New("no route found")
+		return h.options.Router.Route(r)
 	}
 
+	// we have no way of routing the request
+	return nil, nil, errors.New("no route found")
+}
+
+// getAddress returns the address of a random node for the service
+func (h *httpHandler) getAddress(service *api.Service) (string, error) {
 	// get the nodes for this service
 	var nodes []*registry.Node
 	for _, srv := range service.Services {
@@ -5,24 +5,28 @@ import (
 
 	"github.com/micro-community/micro-webui/handler"
 	"github.com/micro-community/micro-webui/handler/api"
+	httph "github.com/micro-community/micro-webui/handler/http"
+	"github.com/micro-community/micro-webui/handler/rpc"
 	"github.com/micro-community/micro-webui/handler/web"
 	"github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/chain"
 	"github.com/micro/micro/v3/service/client"
 	"github.com/micro/micro/v3/service/errors"
 	"github.com/micro/micro/v3/service/logger"
 )
 
 type metaHandler struct {
-	c  client.Client
-	r  router.Router
-	ns string
+	c    client.Client
+	r    router.Router
+	ns   string
+	opts []handler.Option
 }
 
 func (m *metaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info("i'm a meta handler")
 
-	service, err := m.r.Route(r)
+	service, r, err := m.r.Route(r)
 	if err != nil {
 		er := errors.InternalServerError(m.ns, err.Error())
 		w.Header().Set("Content-Type", "application/json")
@@ -31,24 +35,43 @@ func (m *metaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// tell ops which router layer matched
+	if layer, ok := chain.FromContext(r.Context()); ok {
+		w.Header().Set(chain.Header, layer)
+	}
+
+	opts := append([]handler.Option{handler.WithClient(m.c)}, m.opts...)
+
 	switch service.Endpoint.Handler {
 	// web socket handler
 	case web.Handler:
-		web.WithService(service, handler.WithClient(m.c)).ServeHTTP(w, r)
+		web.WithService(service, opts...).ServeHTTP(w, r)
 	// api handler
 	case api.Handler:
-		api.WithService(service, handler.WithClient(m.c)).ServeHTTP(w, r)
+		api.WithService(service, opts...).ServeHTTP(w, r)
+	// http handler
+	case httph.Handler:
+		httph.WithService(service, opts...).ServeHTTP(w, r)
+	// rpc handler, only for routes declaring paths since the
+	// resolver fallback sets rpc for everything it proxies
+	case rpc.Handler:
+		if len(service.Endpoint.Path) > 0 {
+			rpc.WithService(service, opts...).ServeHTTP(w, r)
+			return
+		}
+		web.WithService(service, opts...).ServeHTTP(w, r)
 	default:
-		web.WithService(service, handler.WithClient(m.c)).ServeHTTP(w, r)
+		web.WithService(service, opts...).ServeHTTP(w, r)
 	}
 
 }
 
 // NewMetaHandler is a http.Handler that routes based on endpoint metadata
-func NewMetaHandler(cli client.Client, r router.Router, ns string) http.Handler {
+func NewMetaHandler(cli client.Client, r router.Router, ns string, opts ...handler.Option) http.Handler {
 	return &metaHandler{
-		c:  cli,
-		r:  r,
-		ns: ns,
+		c:    cli,
+		r:    r,
+		ns:   ns,
+		opts: opts,
 	}
 }
@@ -17,6 +17,7 @@
 package handler
 
 import (
+	"github.com/micro-community/micro-webui/handler/cache"
 	"github.com/micro-community/micro-webui/router"
 	"github.com/micro/micro/v3/service/client"
 	"github.com/micro/micro/v3/service/client/grpc"
@@ -31,6 +32,11 @@ type Options struct {
 	Namespace   string
 	Router      router.Router
 	Client      client.Client
+	Cache       *cache.Cache
+	// StripPrefix removes the path the resolver named the service with before proxying web apps
+	StripPrefix bool
+	// RewriteHTML rewrites absolute links in proxied html
+	RewriteHTML bool
 }
 
 type Option func(o *Options)
@@ -78,6 +84,28 @@ func WithClient(c client.Client) Option {
 	}
 }
 
+// WithCache sets the response cache used for GET requests
+func WithCache(c *cache.Cache) Option {
+	return func(o *Options) {
+		o.Cache = c
+	}
+}
+
+// WithStripPrefix strips the path the resolver named the service with from web
+// requests and sends it as the base path
+func WithStripPrefix(b bool) Option {
+	return func(o *Options) {
+		o.StripPrefix = b
+	}
+}
+
+// WithRewriteHTML rewrites absolute links in html responses to include the base path
+func WithRewriteHTML(b bool) Option {
+	return func(o *Options) {
+		o.RewriteHTML = b
+	}
+}
+
 // WithMaxRecvSize specifies max body size
 func WithMaxRecvSize(size int64) Option {
 	return func(o *Options) {
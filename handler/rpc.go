@@ -1,26 +1,43 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/textproto"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/micro-community/micro-webui/helper"
+	"github.com/micro-community/micro-webui/namespace"
 	"github.com/micro-community/micro-webui/resolver"
 	"github.com/micro-community/micro-webui/resolver/subdomain"
 	"github.com/micro-community/micro-webui/server/cors"
 	"github.com/micro/micro/v3/service/client"
+	"github.com/micro/micro/v3/service/context/metadata"
 	"github.com/micro/micro/v3/service/errors"
 )
 
+const (
+	// LatencyHeader is the time the call took in milliseconds
+	LatencyHeader = "X-Micro-Latency"
+	// NodeHeader is the address of the node which served the call
+	NodeHeader = "X-Micro-Node"
+)
+
 type rpcRequest struct {
 	Service  string
 	Endpoint string
 	Method   string
 	Address  string
 	Request  interface{}
+	// Metadata sent with the call, overriding the headers
+	Metadata map[string]string
+	// Timeout of the call as a duration e.g 5s or a number of seconds
+	Timeout interface{}
 }
 
 type rpcHandler struct {
@@ -50,9 +67,6 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(e.Error()))
 	}
 
-	var service, endpoint, address string
-	var request interface{}
-
 	// response content type
 	w.Header().Set("Content-Type", "application/json")
 
@@ -63,10 +77,10 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ct = ct[:idx]
 	}
 
+	var rpcReq rpcRequest
+
 	switch ct {
 	case "application/json":
-		var rpcReq rpcRequest
-
 		d := json.NewDecoder(r.Body)
 		d.UseNumber()
 
@@ -74,72 +88,149 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			badRequest(err.Error())
 			return
 		}
-
-		service = rpcReq.Service
-		endpoint = rpcReq.Endpoint
-		address = rpcReq.Address
-		request = rpcReq.Request
-		if len(endpoint) == 0 {
-			endpoint = rpcReq.Method
-		}
-
-		// JSON as string
-		if req, ok := rpcReq.Request.(string); ok {
-			d := json.NewDecoder(strings.NewReader(req))
-			d.UseNumber()
-
-			if err := d.Decode(&request); err != nil {
-				badRequest("error decoding request string: " + err.Error())
-				return
-			}
-		}
 	default:
 		r.ParseForm()
-		service = r.Form.Get("service")
-		endpoint = r.Form.Get("endpoint")
-		address = r.Form.Get("address")
-		if len(endpoint) == 0 {
-			endpoint = r.Form.Get("method")
+		rpcReq.Service = r.Form.Get("service")
+		rpcReq.Endpoint = r.Form.Get("endpoint")
+		rpcReq.Method = r.Form.Get("method")
+		rpcReq.Address = r.Form.Get("address")
+		if t := r.Form.Get("timeout"); len(t) > 0 {
+			rpcReq.Timeout = t
 		}
 
 		d := json.NewDecoder(strings.NewReader(r.Form.Get("request")))
 		d.UseNumber()
 
-		if err := d.Decode(&request); err != nil {
+		if err := d.Decode(&rpcReq.Request); err != nil {
 			badRequest("error decoding request string: " + err.Error())
 			return
 		}
+
+		if md := r.Form.Get("metadata"); len(md) > 0 {
+			if err := json.Unmarshal([]byte(md), &rpcReq.Metadata); err != nil {
+				badRequest("error decoding metadata: " + err.Error())
+				return
+			}
+		}
 	}
 
-	if len(service) == 0 {
-		badRequest("invalid service")
+	timeout, err := rpcReq.validate()
+	if err != nil {
+		badRequest(err.Error())
 		return
 	}
 
-	if len(endpoint) == 0 {
-		badRequest("invalid endpoint")
+	start := time.Now()
+	response, node, err := h.call(r, &rpcReq, timeout)
+
+	w.Header().Set(LatencyHeader, strconv.FormatFloat(float64(time.Since(start))/float64(time.Millisecond), 'f', 3, 64))
+	if len(node) > 0 {
+		w.Header().Set(NodeHeader, node)
+	}
+
+	if err != nil {
+		ce := errors.Parse(err.Error())
+		switch ce.Code {
+		case 0:
+			// assuming it's totally screwed
+			ce.Code = 500
+			ce.Id = "micro.rpc"
+			ce.Status = http.StatusText(500)
+			ce.Detail = "error during request: " + ce.Detail
+			w.WriteHeader(500)
+		default:
+			w.WriteHeader(int(ce.Code))
+		}
+		w.Write([]byte(ce.Error()))
 		return
 	}
 
+	b, _ := response.MarshalJSON()
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.Write(b)
+}
+
+// validate normalizes the request, returning its timeout
+func (r *rpcRequest) validate() (time.Duration, error) {
+	// JSON as string
+	if req, ok := r.Request.(string); ok {
+		d := json.NewDecoder(strings.NewReader(req))
+		d.UseNumber()
+
+		if err := d.Decode(&r.Request); err != nil {
+			return 0, fmt.Errorf("error decoding request string: %v", err)
+		}
+	}
+
+	if len(r.Endpoint) == 0 {
+		r.Endpoint = r.Method
+	}
+
+	if len(r.Service) == 0 {
+		return 0, fmt.Errorf("invalid service")
+	}
+
+	if len(r.Endpoint) == 0 {
+		return 0, fmt.Errorf("invalid endpoint")
+	}
+
+	timeout, err := ParseTimeout(r.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %v", err)
+	}
+
+	return timeout, nil
+}
+
+// call makes the request, returning the response and the address of the node which served it
+func (h *rpcHandler) call(r *http.Request, rpcReq *rpcRequest, timeout time.Duration) (json.RawMessage, string, error) {
 	// create request/response
 	var response json.RawMessage
-	var err error
-	req := client.DefaultClient.NewRequest(service, endpoint, request, client.WithContentType("application/json"))
+	req := client.DefaultClient.NewRequest(rpcReq.Service, rpcReq.Endpoint, rpcReq.Request, client.WithContentType("application/json"))
 
 	// create context
 	ctx := helper.RequestToContext(r)
 
-	var opts []client.CallOption
+	if len(rpcReq.Metadata) > 0 {
+		md := make(metadata.Metadata, len(rpcReq.Metadata))
+		for k, v := range rpcReq.Metadata {
+			md[textproto.CanonicalMIMEHeaderKey(k)] = v
+		}
+		ctx = metadata.MergeContext(ctx, md, true)
+	}
 
-	timeout, _ := strconv.Atoi(r.Header.Get("Timeout"))
+	// call within the namespace the request was scoped to, the metadata can't change it
+	if ns := namespace.FromContext(r.Context()); len(ns) > 0 {
+		ctx = namespace.ContextWithNamespace(ctx, ns)
+	}
+
+	// the node of each attempt, the last served the call
+	var mtx sync.Mutex
+	var node string
+
+	opts := []client.CallOption{
+		client.WithCallWrapper(func(next client.CallFunc) client.CallFunc {
+			return func(ctx context.Context, addr string, req client.Request, rsp interface{}, opts client.CallOptions) error {
+				mtx.Lock()
+				node = addr
+				mtx.Unlock()
+				return next(ctx, addr, req, rsp, opts)
+			}
+		}),
+	}
+
+	if timeout == 0 {
+		t, _ := strconv.Atoi(r.Header.Get("Timeout"))
+		timeout = time.Duration(t) * time.Second
+	}
 	// set timeout
 	if timeout > 0 {
-		opts = append(opts, client.WithRequestTimeout(time.Duration(timeout)*time.Second))
+		opts = append(opts, client.WithRequestTimeout(timeout))
 	}
 
 	// remote call
-	if len(address) > 0 {
-		opts = append(opts, client.WithAddress(address))
+	if len(rpcReq.Address) > 0 {
+		opts = append(opts, client.WithAddress(rpcReq.Address))
 	}
 
 	// since services can be running in many domains, we'll use the resolver to determine the domain
@@ -150,28 +241,43 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// tenants only call the services in their registry domain
+	if ns := namespace.FromContext(r.Context()); len(ns) > 0 {
+		opts = append(opts, client.WithNetwork(ns))
+	}
+
 	// remote call
-	err = client.DefaultClient.Call(ctx, req, &response, opts...)
-	if err != nil {
-		ce := errors.Parse(err.Error())
-		switch ce.Code {
-		case 0:
-			// assuming it's totally screwed
-			ce.Code = 500
-			ce.Id = "micro.rpc"
-			ce.Status = http.StatusText(500)
-			ce.Detail = "error during request: " + ce.Detail
-			w.WriteHeader(500)
-		default:
-			w.WriteHeader(int(ce.Code))
+	err := client.DefaultClient.Call(ctx, req, &response, opts...)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	return response, node, err
+}
+
+// ParseTimeout reads a timeout given as a duration e.g 5s or a number of seconds
+func ParseTimeout(v interface{}) (time.Duration, error) {
+	if v == nil {
+		return 0, nil
+	}
+
+	s := fmt.Sprint(v)
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("negative timeout %s", s)
 		}
-		w.Write([]byte(ce.Error()))
-		return
+		return time.Duration(n * float64(time.Second)), nil
 	}
 
-	b, _ := response.MarshalJSON()
-	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
-	w.Write(b)
+	d, err := time.ParseDuration(s)
+	if err == nil && d < 0 {
+		return 0, fmt.Errorf("negative timeout %s", s)
+	}
+	return d, err
 }
 
 // NewRPCHandler returns an initialized RPC handler
@@ -0,0 +1,321 @@
+// Package rpc is a http handler which transcodes http/json requests to rpc
+// using the google.api.http path variables and body selector set by the router
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/micro-community/micro-webui/handler"
+	"github.com/micro-community/micro-webui/helper/ctx"
+	rutil "github.com/micro-community/micro-webui/helper/registry"
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/transform"
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/client"
+	"github.com/micro/micro/v3/service/context/metadata"
+	"github.com/micro/micro/v3/service/errors"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+const (
+	Handler = "rpc"
+
+	// metadata set by the router, keys are title cased by the metadata package
+	fieldPrefix = "X-Api-Field-"
+	bodyKey     = "X-Api-Body"
+)
+
+type rpcHandler struct {
+	opts handler.Options
+	s    *api.Service
+}
+
+func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var service *api.Service
+
+	if h.s != nil {
+		// we were given the service
+		service = h.s
+	} else if h.opts.Router != nil {
+		// try get service from router
+		s, rr, err := h.opts.Router.Route(r)
+		if err != nil {
+			writeError(w, errors.InternalServerError("go.micro.api", err.Error()))
+			return
+		}
+		service = s
+		r = rr
+	} else {
+		// we have no way of routing the request
+		writeError(w, errors.InternalServerError("go.micro.api", "no route found"))
+		return
+	}
+
+	// apply the route transform rules
+	w, r = transform.FromContext(r.Context()).Apply(w, r)
+
+	var hd http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, service)
+	})
+
+	// serve cacheable requests from the cache
+	if h.opts.Cache != nil {
+		hd = h.opts.Cache.Handler(service, hd)
+	}
+
+	hd.ServeHTTP(w, r)
+}
+
+// serve builds the request message and calls the routed endpoint
+func (h *rpcHandler) serve(w http.ResponseWriter, r *http.Request, service *api.Service) {
+	bsize := handler.DefaultMaxRecvSize
+	if h.opts.MaxRecvSize > 0 {
+		bsize = h.opts.MaxRecvSize
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, bsize)
+
+	var schema *registry.Value
+	if ep := rutil.FindEndpoint(service.Services, service.Endpoint.Name); ep != nil {
+		schema = ep.Request
+	}
+
+	request, err := requestPayload(r, schema)
+	if err != nil {
+		writeError(w, errors.BadRequest("go.micro.api", err.Error()))
+		return
+	}
+
+	c := h.opts.Client
+	req := c.NewRequest(service.Name, service.Endpoint.Name, request, client.WithContentType("application/json"))
+
+	var response json.RawMessage
+	if err := c.Call(ctx.FromRequest(r), req, &response, client.WithRouter(router.New(service.Services))); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	b, _ := response.MarshalJSON()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.Write(b)
+}
+
+// requestPayload merges the body, query parameters and path variables into a json object
+func requestPayload(r *http.Request, schema *registry.Value) (map[string]interface{}, error) {
+	md, _ := metadata.FromContext(r.Context())
+	body := md[bodyKey]
+
+	// the path variables named as in the template, metadata title cases them
+	vars, hasVars := router.VarsFromContext(r.Context())
+	if hasVars {
+		body = vars.Body
+	}
+
+	payload := make(map[string]interface{})
+
+	// 1. the body according to the selector, "*" or "" is the whole message
+	var values url.Values
+
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		values = r.PostForm
+	default:
+		if r.Body == nil {
+			break
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(b)) == 0 {
+			break
+		}
+
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return nil, err
+		}
+
+		if body == "*" || len(body) == 0 {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("request body must be a json object")
+			}
+			payload = obj
+		} else {
+			setField(payload, fieldName(schema, body), v)
+		}
+	}
+
+	for k, v := range values {
+		if body == "*" || len(body) == 0 {
+			setField(payload, fieldName(schema, k), coerce(schema, k, v))
+		} else {
+			setField(payload, fieldName(schema, body+"."+k), coerce(schema, body+"."+k, v))
+		}
+	}
+
+	// 2. query parameters fill in anything not already set, unless the body is the whole message
+	if body != "*" {
+		for k, v := range r.URL.Query() {
+			name := fieldName(schema, k)
+			if hasField(payload, name) {
+				continue
+			}
+			setField(payload, name, coerce(schema, k, v))
+		}
+	}
+
+	// 3. path variables always win
+	if hasVars {
+		for path, v := range vars.Fields {
+			setField(payload, path, coerce(schema, path, []string{v}))
+		}
+		return payload, nil
+	}
+
+	for k, v := range md {
+		if !strings.HasPrefix(k, fieldPrefix) {
+			continue
+		}
+		path := fieldName(schema, strings.TrimPrefix(k, fieldPrefix))
+		setField(payload, path, coerce(schema, path, []string{v}))
+	}
+
+	return payload, nil
+}
+
+// fieldName restores the case of a field path which may have been title cased in metadata
+func fieldName(schema *registry.Value, path string) string {
+	parts := strings.Split(path, ".")
+
+	v := schema
+	for i, p := range parts {
+		var f *registry.Value
+		if v != nil {
+			f = rutil.Field(v, p)
+		}
+		if f != nil {
+			parts[i] = f.Name
+		} else if len(p) > 0 {
+			// proto field names start lower case
+			parts[i] = strings.ToLower(p[:1]) + p[1:]
+		}
+		v = f
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// coerce converts string values to the json type of the field
+func coerce(schema *registry.Value, path string, vals []string) interface{} {
+	var typ string
+	if f := rutil.Field(schema, path); f != nil {
+		typ = f.Type
+	}
+
+	conv := func(s string) interface{} {
+		et := rutil.ElemType(typ)
+		switch {
+		case et == "bool":
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		case rutil.IsNumber(et):
+			if _, err := strconv.ParseFloat(s, 64); err == nil {
+				return json.Number(s)
+			}
+		}
+		return s
+	}
+
+	if rutil.IsRepeated(typ) || len(vals) > 1 {
+		list := make([]interface{}, 0, len(vals))
+		for _, v := range vals {
+			list = append(list, conv(v))
+		}
+		return list
+	}
+
+	if len(vals) == 0 {
+		return nil
+	}
+
+	return conv(vals[0])
+}
+
+// setField sets a nested field path e.g book.name
+func setField(m map[string]interface{}, path string, v interface{}) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = v
+}
+
+func hasField(m map[string]interface{}, path string) bool {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		m = next
+	}
+	_, ok := m[parts[len(parts)-1]]
+	return ok
+}
+
+// writeError maps a micro error to its http status
+func writeError(w http.ResponseWriter, err error) {
+	ce := errors.Parse(err.Error())
+	if ce.Code == 0 {
+		// assuming it's totally screwed
+		ce.Code = http.StatusInternalServerError
+		ce.Id = "go.micro.api"
+		ce.Status = http.StatusText(http.StatusInternalServerError)
+		ce.Detail = "error during request: " + ce.Detail
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(ce.Code))
+	w.Write([]byte(ce.Error()))
+}
+
+func (h *rpcHandler) String() string {
+	return "rpc"
+}
+
+// NewHandler returns a http to rpc transcoding handler
+func NewHandler(opts ...handler.Option) handler.Handler {
+	return &rpcHandler{
+		opts: handler.NewOptions(opts...),
+	}
+}
+
+// WithService creates a handler with a service
+func WithService(s *api.Service, opts ...handler.Option) handler.Handler {
+	return &rpcHandler{
+		opts: handler.NewOptions(opts...),
+		s:    s,
+	}
+}
@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro/micro/v3/service/context/metadata"
+	"github.com/micro/micro/v3/service/errors"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+var bookSchema = &registry.Value{
+	Name: "UpdateBookRequest",
+	Type: "UpdateBookRequest",
+	Values: []*registry.Value{
+		{Name: "shelfId", Type: "int64"},
+		{Name: "force", Type: "bool"},
+		{Name: "tags", Type: "[]string"},
+		{Name: "book", Type: "Book", Values: []*registry.Value{
+			{Name: "name", Type: "string"},
+			{Name: "title", Type: "string"},
+		}},
+	},
+}
+
+func TestRequestPayload(t *testing.T) {
+	testData := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		fields map[string]string
+		sel    string
+		schema *registry.Value
+		expect string
+	}{
+		{
+			name:   "path and query",
+			method: "GET",
+			url:    "/v1/shelves/12/books?force=true&tags=a&tags=b",
+			fields: map[string]string{"shelfId": "12"},
+			schema: bookSchema,
+			expect: `{"force":true,"shelfId":12,"tags":["a","b"]}`,
+		},
+		{
+			name:   "nested path variable",
+			method: "GET",
+			url:    "/v1/shelves/1/books/abc",
+			fields: map[string]string{"book.name": "shelves/1/books/abc"},
+			schema: bookSchema,
+			expect: `{"book":{"name":"shelves/1/books/abc"}}`,
+		},
+		{
+			name:   "body field",
+			method: "PATCH",
+			url:    "/v1/shelves/1/books/abc?force=1",
+			body:   `{"title":"Go"}`,
+			sel:    "book",
+			fields: map[string]string{"book.name": "abc", "shelfId": "1"},
+			schema: bookSchema,
+			expect: `{"book":{"name":"abc","title":"Go"},"force":true,"shelfId":1}`,
+		},
+		{
+			name:   "whole body",
+			method: "POST",
+			url:    "/v1/shelves/1/books?shelfId=2&force=true",
+			body:   `{"shelfId":3,"book":{"title":"Go"}}`,
+			sel:    "*",
+			fields: map[string]string{"shelfId": "1"},
+			schema: bookSchema,
+			expect: `{"book":{"title":"Go"},"shelfId":1}`,
+		},
+		{
+			name:   "path variables without schema",
+			method: "GET",
+			url:    "/v1/users/john?page_size=10",
+			fields: map[string]string{"display_name": "john", "user.ID": "1"},
+			expect: `{"display_name":"john","page_size":"10","user":{"ID":"1"}}`,
+		},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			r := httptest.NewRequest(d.method, d.url, strings.NewReader(d.body))
+			r.Header.Set("Content-Type", "application/json")
+
+			// mirror what the routers set
+			md := metadata.Metadata{"x-api-body": d.sel}
+			for k, v := range d.fields {
+				md["x-api-field-"+k] = v
+			}
+			ctx := metadata.NewContext(r.Context(), md)
+			ctx = router.NewVarsContext(ctx, &router.Vars{Fields: d.fields, Body: d.sel})
+			r = r.WithContext(ctx)
+
+			payload, err := requestPayload(r, d.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, _ := json.Marshal(payload)
+			if string(b) != d.expect {
+				t.Fatalf("Expected %s got %s", d.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	testData := []struct {
+		err  error
+		code int
+	}{
+		{errors.NotFound("go.micro.srv.foo", "not found"), 404},
+		{errors.Forbidden("go.micro.srv.foo", "denied"), 403},
+		{errors.BadRequest("go.micro.srv.foo", "bad"), 400},
+		{errors.Timeout("go.micro.srv.foo", "slow"), 408},
+		{errors.New("", "plain error", 0), 500},
+	}
+
+	for _, d := range testData {
+		w := httptest.NewRecorder()
+		writeError(w, d.err)
+
+		if w.Code != d.code {
+			t.Errorf("Expected %d for %v got %d", d.code, d.err, w.Code)
+		}
+
+		e := errors.Parse(w.Body.String())
+		if int(e.Code) != d.code {
+			t.Errorf("Expected error body code %d got %d", d.code, e.Code)
+		}
+	}
+}
@@ -8,10 +8,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/micro-community/micro-webui/namespace"
 	"github.com/micro/micro/v3/profile"
 	"github.com/micro/micro/v3/service"
 	"github.com/micro/micro/v3/service/context/metadata"
+	"github.com/micro/micro/v3/service/registry"
 )
 
 type TestHandler struct {
@@ -85,3 +88,126 @@ func TestRPCHandler(t *testing.T) {
 		t.Fatalf("Expected 200 response got %d %s", w.Code, w.Body.String())
 	}
 }
+
+// MetadataHandler is registered apart from TestHandler as tests share the server
+type MetadataHandler struct {
+	*TestHandler
+}
+
+func TestRPCHandlerMetadata(t *testing.T) {
+	profile.Test.Setup(nil)
+
+	srv := service.New(
+		service.Name("test.metadata"),
+	)
+
+	// the body metadata overrides the headers
+	srv.Server().Handle(
+		srv.Server().NewHandler(&MetadataHandler{&TestHandler{t, metadata.Metadata{"Foo": "Baz", "Trace-Id": "1"}}}),
+	)
+
+	if err := srv.Server().Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer srv.Server().Stop()
+
+	rb := `{"service": "test.metadata", "endpoint": "MetadataHandler.Exec", "request": {}, "metadata": {"foo": "Baz", "trace-id": "1"}, "timeout": "5s"}`
+
+	req, err := http.NewRequest("POST", "/rpc", bytes.NewBufferString(rb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Foo", "Bar")
+
+	w := httptest.NewRecorder()
+	NewRPCHandler(nil).ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected 200 response got %d %s", w.Code, w.Body.String())
+	}
+	svc, err := registry.GetService("test.metadata")
+	if err != nil || len(svc) == 0 || len(svc[0].Nodes) == 0 {
+		t.Fatalf("Expected the service to be registered got %v", err)
+	}
+	if node := w.Header().Get(NodeHeader); node != svc[0].Nodes[0].Address {
+		t.Fatalf("Expected node %s got %q", svc[0].Nodes[0].Address, node)
+	}
+	if len(w.Header().Get(LatencyHeader)) == 0 {
+		t.Fatal("Expected the latency header")
+	}
+}
+
+func TestRPCHandlerNamespace(t *testing.T) {
+	profile.Test.Setup(nil)
+
+	srv := service.New(
+		service.Name("test.namespace"),
+	)
+
+	srv.Server().Handle(
+		srv.Server().NewHandler(&NamespaceHandler{&TestHandler{t, nil}}),
+	)
+
+	if err := srv.Server().Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer srv.Server().Stop()
+
+	call := func(ns string) *httptest.ResponseRecorder {
+		rb := `{"service": "test.namespace", "endpoint": "NamespaceHandler.Exec", "request": {}}`
+
+		req, err := http.NewRequest("POST", "/rpc", bytes.NewBufferString(rb))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(namespace.ContextWithNamespace(req.Context(), ns))
+
+		w := httptest.NewRecorder()
+		NewRPCHandler(nil).ServeHTTP(w, req)
+		return w
+	}
+
+	if w := call(registry.DefaultDomain); w.Code != 200 {
+		t.Fatalf("Expected 200 response got %d %s", w.Code, w.Body.String())
+	}
+
+	// the service is only in the default domain
+	if w := call("tenant"); w.Code == 200 {
+		t.Fatalf("Expected the tenant call to fail got %s", w.Body.String())
+	}
+}
+
+// NamespaceHandler is registered apart from TestHandler as tests share the server
+type NamespaceHandler struct {
+	*TestHandler
+}
+
+func TestParseTimeout(t *testing.T) {
+	testData := []struct {
+		value  interface{}
+		expect time.Duration
+		err    bool
+	}{
+		{nil, 0, false},
+		{"", 0, false},
+		{json.Number("5"), 5 * time.Second, false},
+		{"1.5", 1500 * time.Millisecond, false},
+		{"250ms", 250 * time.Millisecond, false},
+		{"-1", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, d := range testData {
+		v, err := ParseTimeout(d.value)
+		if (err != nil) != d.err {
+			t.Fatalf("Expected error %v for %v got %v", d.err, d.value, err)
+		}
+		if v != d.expect {
+			t.Fatalf("Expected %v for %v got %v", d.expect, d.value, v)
+		}
+	}
+}
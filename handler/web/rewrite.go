@@ -0,0 +1,127 @@
+package web
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// matches the path attribute of a Set-Cookie header
+	cookiePathRe = regexp.MustCompile(`(?i)(;\s*path=)([^;]*)`)
+	// matches absolute links in html attributes
+	htmlLinkRe = regexp.MustCompile(`(?i)(\s(?:href|src|action)\s*=\s*["'])(/[^"']*)`)
+)
+
+// rewriter fixes up responses from a web app mounted at a base path
+type rewriter struct {
+	// the path prefix stripped from the request e.g /greeter
+	base string
+	// the backend host
+	host string
+	html bool
+}
+
+// prefix adds the base path to an absolute path, leaving protocol relative urls
+func (rw *rewriter) prefix(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	if p == rw.base || strings.HasPrefix(p, rw.base+"/") {
+		return p
+	}
+	return rw.base + p
+}
+
+func (rw *rewriter) ModifyResponse(rsp *http.Response) error {
+	rw.location(rsp)
+	rw.cookies(rsp)
+
+	if rw.html {
+		return rw.rewriteHTML(rsp)
+	}
+
+	return nil
+}
+
+// location rewrites redirects pointing at the backend
+func (rw *rewriter) location(rsp *http.Response) {
+	loc := rsp.Header.Get("Location")
+	if len(loc) == 0 {
+		return
+	}
+
+	u, err := url.Parse(loc)
+	if err != nil {
+		return
+	}
+
+	// absolute urls are only rewritten when they point at the backend itself
+	if u.IsAbs() {
+		if u.Host != rw.host {
+			return
+		}
+		u.Scheme = ""
+		u.Host = ""
+	}
+
+	u.Path = rw.prefix(u.Path)
+	if len(u.RawPath) > 0 {
+		u.RawPath = rw.prefix(u.RawPath)
+	}
+
+	rsp.Header.Set("Location", u.String())
+}
+
+// cookies scopes cookie paths to the base path
+func (rw *rewriter) cookies(rsp *http.Response) {
+	cookies := rsp.Header.Values("Set-Cookie")
+	if len(cookies) == 0 {
+		return
+	}
+
+	rsp.Header.Del("Set-Cookie")
+
+	for _, c := range cookies {
+		c = cookiePathRe.ReplaceAllStringFunc(c, func(m string) string {
+			parts := cookiePathRe.FindStringSubmatch(m)
+			return parts[1] + rw.prefix(strings.TrimSpace(parts[2]))
+		})
+		rsp.Header.Add("Set-Cookie", c)
+	}
+}
+
+// rewriteHTML prefixes absolute href, src and action attributes
+func (rw *rewriter) rewriteHTML(rsp *http.Response) error {
+	ct, _, err := mime.ParseMediaType(rsp.Header.Get("Content-Type"))
+	if err != nil || ct != "text/html" {
+		return nil
+	}
+
+	// we can't rewrite what we can't read
+	if ce := rsp.Header.Get("Content-Encoding"); len(ce) > 0 && ce != "identity" {
+		return nil
+	}
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	b = htmlLinkRe.ReplaceAllFunc(b, func(m []byte) []byte {
+		parts := htmlLinkRe.FindSubmatch(m)
+		return []byte(string(parts[1]) + rw.prefix(string(parts[2])))
+	})
+
+	rsp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	rsp.ContentLength = int64(len(b))
+	rsp.Header.Set("Content-Length", strconv.Itoa(len(b)))
+
+	return nil
+}
@@ -29,6 +29,8 @@ import (
 	"strings"
 
 	"github.com/micro-community/micro-webui/handler"
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/router/transform"
 	"github.com/micro/micro/v3/service/api"
 	"github.com/micro/micro/v3/service/registry"
 )
@@ -43,12 +45,31 @@ type webHandler struct {
 }
 
 func (wh *webHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	service, err := wh.getService(r)
+	service, r, err := wh.getService(r)
 	if err != nil {
 		w.WriteHeader(500)
 		return
 	}
 
+	// never trust a base path sent by the client
+	if len(r.Header.Get(transform.BasePathHeader)) > 0 {
+		r = r.Clone(r.Context())
+		r.Header.Del(transform.BasePathHeader)
+	}
+
+	// apply the route transform rules
+	w, r = transform.FromContext(r.Context()).Apply(w, r)
+
+	// strip the path the resolver named the service with unless the rules already set a base path
+	if wh.opts.StripPrefix && len(r.Header.Get(transform.BasePathHeader)) == 0 {
+		if rp, ok := resolver.FromContext(r.Context()); ok && len(rp.Prefix) > 0 {
+			strip := &transform.Rules{
+				Request: []*transform.Rule{{Type: transform.StripPrefix, Value: rp.Prefix}},
+			}
+			w, r = strip.Apply(w, r)
+		}
+	}
+
 	if len(service) == 0 {
 		w.WriteHeader(404)
 		return
@@ -65,11 +86,29 @@ func (wh *webHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	httputil.NewSingleHostReverseProxy(rp).ServeHTTP(w, r)
+	proxy := httputil.NewSingleHostReverseProxy(rp)
+
+	// fix up redirects, cookies and links for apps mounted at a base path
+	if base := r.Header.Get(transform.BasePathHeader); len(base) > 0 {
+		rw := &rewriter{base: base, host: rp.Host, html: wh.opts.RewriteHTML}
+		proxy.ModifyResponse = rw.ModifyResponse
+
+		// ask for an uncompressed body so it can be rewritten
+		if rw.html {
+			director := proxy.Director
+			proxy.Director = func(req *http.Request) {
+				director(req)
+				req.Header.Del("Accept-Encoding")
+			}
+		}
+	}
+
+	proxy.ServeHTTP(w, r)
 }
 
-// getService returns the service for this request from the selector
-func (wh *webHandler) getService(r *http.Request) (string, error) {
+// getService returns the service for this request from the selector and the
+// request annotated with what the route matched
+func (wh *webHandler) getService(r *http.Request) (string, *http.Request, error) {
 	var service *api.Service
 
 	if wh.s != nil {
@@ -77,14 +116,15 @@ func (wh *webHandler) getService(r *http.Request) (string, error) {
 		service = wh.s
 	} else if wh.opts.Router != nil {
 		// try get service from router
-		s, err := wh.opts.Router.Route(r)
+		s, rr, err := wh.opts.Router.Route(r)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		service = s
+		r = rr
 	} else {
 		// we have no way of routing the request
-		return "", errors.New("no route found")
+		return "", nil, errors.New("no route found")
 	}
 
 	// get the nodes
@@ -93,13 +133,13 @@ func (wh *webHandler) getService(r *http.Request) (string, error) {
 		nodes = append(nodes, srv.Nodes...)
 	}
 	if len(nodes) == 0 {
-		return "", errors.New("no route found")
+		return "", nil, errors.New("no route found")
 	}
 
 	// select a random node
 	node := nodes[rand.Int()%len(nodes)]
 
-	return fmt.Sprintf("http://%s", node.Address), nil
+	return fmt.Sprintf("http://%s", node.Address), r, nil
 }
 
 // serveWebSocket used to serve a web socket proxied connection
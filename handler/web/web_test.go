@@ -0,0 +1,115 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro-community/micro-webui/handler"
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/router/transform"
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+func testService(t *testing.T, m *http.ServeMux) (*api.Service, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go http.Serve(l, m)
+
+	return &api.Service{
+		Name:     "go.micro.web.greeter",
+		Endpoint: &api.Endpoint{Handler: Handler},
+		Services: []*registry.Service{{
+			Name:  "go.micro.web.greeter",
+			Nodes: []*registry.Node{{Id: "greeter-1", Address: l.Addr().String()}},
+		}},
+	}, func() { l.Close() }
+}
+
+// resolved returns a request resolved by the path with the prefix
+func resolved(method, path, prefix string) *http.Request {
+	r := httptest.NewRequest(method, path, nil)
+	return r.WithContext(resolver.NewContext(r.Context(), &resolver.Endpoint{Path: path, Prefix: prefix}))
+}
+
+func TestStripPrefix(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get(transform.BasePathHeader)))
+	})
+
+	svc, stop := testService(t, m)
+	defer stop()
+
+	testData := []struct {
+		name   string
+		req    *http.Request
+		expect string
+	}{
+		{"path resolver", resolved("GET", "/greeter/foo", "/greeter"), "/foo /greeter"},
+		{"versioned path resolver", resolved("GET", "/v1/greeter/foo", "/v1/greeter"), "/foo /v1/greeter"},
+		// e.g the host, subdomain and header resolvers
+		{"service not in the path", resolved("GET", "/greeter/foo", ""), "/greeter/foo "},
+		// routes declaring paths aren't resolved
+		{"route", httptest.NewRequest("GET", "/greeter/foo", nil), "/greeter/foo "},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			d.req.Header.Set(transform.BasePathHeader, "/spoofed")
+
+			WithService(svc, handler.WithStripPrefix(true)).ServeHTTP(w, d.req)
+
+			if w.Code != 200 {
+				t.Fatalf("Expected 200 response got %d", w.Code)
+			}
+			if w.Body.String() != d.expect {
+				t.Fatalf("Expected %q got %q", d.expect, w.Body.String())
+			}
+			if d.req.Header.Get(transform.BasePathHeader) != "/spoofed" {
+				t.Fatal("Expected the request to be left as it was")
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+		http.Redirect(w, r, "/home", http.StatusFound)
+	})
+	m.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<a href="/about">About</a><img src="//cdn.example.com/x.png"><script src="/greeter/app.js"></script>`))
+	})
+
+	svc, stop := testService(t, m)
+	defer stop()
+
+	h := WithService(svc, handler.WithStripPrefix(true), handler.WithRewriteHTML(true))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, resolved("GET", "/greeter/login", "/greeter"))
+
+	if loc := w.Header().Get("Location"); loc != "/greeter/home" {
+		t.Fatalf("Expected location /greeter/home got %s", loc)
+	}
+	if c := w.Header().Get("Set-Cookie"); c != "session=1; Path=/greeter/" {
+		t.Fatalf("Expected cookie path /greeter/ got %s", c)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, resolved("GET", "/greeter/home", "/greeter"))
+
+	expect := `<a href="/greeter/about">About</a><img src="//cdn.example.com/x.png"><script src="/greeter/app.js"></script>`
+	if w.Body.String() != expect {
+		t.Fatalf("Expected body %s got %s", expect, w.Body.String())
+	}
+}
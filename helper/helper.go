@@ -24,9 +24,14 @@ func ACMEHosts(ctx *cli.Context) []string {
 	return hosts
 }
 
+// RequestToContext returns the request context with the headers added to its metadata,
+// the call is cancelled with the request
 func RequestToContext(r *http.Request) context.Context {
-	ctx := context.Background()
-	md := make(metadata.Metadata)
+	ctx := r.Context()
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		md = make(metadata.Metadata)
+	}
 	for k, v := range r.Header {
 		md[k] = strings.Join(v, ",")
 	}
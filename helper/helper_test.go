@@ -1,6 +1,7 @@
 package helper
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -26,6 +27,14 @@ func TestRequestToContext(t *testing.T) {
 		},
 	}
 
+	// the request context is kept
+	parent, cancel := context.WithCancel(metadata.NewContext(context.Background(), metadata.Metadata{"Foo3": "bar"}))
+	req := (&http.Request{Header: http.Header{"Foo1": []string{"bar"}}}).WithContext(parent)
+	testData = append(testData, struct {
+		request *http.Request
+		expect  metadata.Metadata
+	}{req, metadata.Metadata{"Foo1": "bar", "Foo3": "bar"}})
+
 	for _, d := range testData {
 		ctx := RequestToContext(d.request)
 		md, ok := metadata.FromContext(ctx)
@@ -38,4 +47,9 @@ func TestRequestToContext(t *testing.T) {
 			}
 		}
 	}
+
+	cancel()
+	if err := RequestToContext(req).Err(); err != context.Canceled {
+		t.Fatalf("Expected the context to be cancelled with the request got %v", err)
+	}
 }
@@ -0,0 +1,443 @@
+// Package openapi generates OpenAPI 3 documents from registry services
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	rutil "github.com/micro-community/micro-webui/helper/registry"
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// Version of the OpenAPI specification generated
+const Version = "3.0.3"
+
+// RPCPath is where endpoints without http annotations are called
+var RPCPath = "/rpc"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       *Info                `json:"info"`
+	Servers    []*Server            `json:"servers,omitempty"`
+	Tags       []*Tag               `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Info describes the api
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is where the api is served
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups the operations of a service
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem holds the operations of a path by method
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+}
+
+// Operation is an endpoint called with a method and path
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody of an operation
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response of an operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components are the schemas referenced by operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema describes a value, an empty schema is any value
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Generate returns a document describing the versions of the services,
+// the first version with an endpoint describes it
+func Generate(info *Info, services []*registry.Service) *Document {
+	g := &generator{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Servers:    []*Server{{URL: "/"}},
+			Paths:      make(map[string]*PathItem),
+			Components: &Components{Schemas: make(map[string]*Schema)},
+		},
+		ids: make(map[string]int),
+	}
+
+	names := make(map[string][]*registry.Service)
+	for _, s := range services {
+		names[s.Name] = append(names[s.Name], s)
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		g.doc.Tags = append(g.doc.Tags, &Tag{Name: name})
+		g.service(name, names[name])
+	}
+
+	if len(g.rpc) > 0 {
+		g.addRPC()
+	}
+
+	return g.doc
+}
+
+type generator struct {
+	doc *Document
+	// calls and responses of endpoints without http annotations
+	rpc, rsp []*Schema
+	// operation ids used
+	ids map[string]int
+}
+
+func (g *generator) service(name string, versions []*registry.Service) {
+	var endpoints []*registry.Endpoint
+	var values []*registry.Value
+	seen := make(map[string]bool)
+
+	for _, s := range versions {
+		for _, ep := range s.Endpoints {
+			if seen[ep.Name] {
+				continue
+			}
+			seen[ep.Name] = true
+			endpoints = append(endpoints, ep)
+			values = append(values, ep.Request, ep.Response)
+		}
+	}
+
+	sc := &schemas{service: name, messages: rutil.Messages(values...), components: g.doc.Components.Schemas}
+
+	for _, ep := range endpoints {
+		req, rsp := sc.ref(ep.Request), sc.ref(ep.Response)
+
+		if g.http(name, ep, sc, req, rsp) {
+			continue
+		}
+
+		g.rpc = append(g.rpc, &Schema{
+			Title:    name + " " + ep.Name,
+			Type:     "object",
+			Required: []string{"service", "endpoint"},
+			Properties: map[string]*Schema{
+				"service":  {Type: "string", Enum: []string{name}},
+				"endpoint": {Type: "string", Enum: []string{ep.Name}},
+				"request":  req,
+			},
+		})
+		g.rsp = append(g.rsp, rsp)
+	}
+}
+
+// http adds the operations of an endpoint with http annotations, reporting whether it has any
+func (g *generator) http(service string, ep *registry.Endpoint, sc *schemas, req, rsp *Schema) bool {
+	end := api.Decode(ep.Metadata)
+	if end == nil || len(end.Name) == 0 {
+		return false
+	}
+	end.Body = ep.Metadata["body"]
+	if api.Validate(end) != nil {
+		return false
+	}
+
+	methods := end.Method
+	if len(methods) == 0 {
+		methods = []string{"POST"}
+	}
+
+	var added bool
+
+	for _, p := range end.Path {
+		// regular expressions can't be described
+		if !strings.HasPrefix(p, "/") {
+			continue
+		}
+		path, vars := template(p)
+
+		for _, m := range methods {
+			op := &Operation{
+				OperationID: g.id(service + "." + ep.Name),
+				Summary:     ep.Name,
+				Description: end.Description,
+				Tags:        []string{service},
+				Responses:   responses(rsp),
+			}
+
+			for _, v := range vars {
+				op.Parameters = append(op.Parameters, &Parameter{
+					Name:     v,
+					In:       "path",
+					Required: true,
+					Schema:   sc.field(ep.Request, v),
+				})
+			}
+
+			body := end.Body
+			switch m {
+			case "GET", "HEAD", "DELETE", "OPTIONS":
+				body = "-"
+			}
+
+			switch body {
+			case "", "*":
+				op.RequestBody = &RequestBody{Required: true, Content: jsonContent(req)}
+			default:
+				if body != "-" {
+					op.RequestBody = &RequestBody{Required: true, Content: jsonContent(sc.field(ep.Request, body))}
+				}
+				op.Parameters = append(op.Parameters, sc.query(ep.Request, append(vars, body))...)
+			}
+
+			if g.add(path, m, op) {
+				added = true
+			}
+		}
+	}
+
+	return added
+}
+
+// add sets the operation of the method unless the path already has one
+func (g *generator) add(path, method string, op *Operation) bool {
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+	}
+
+	var o **Operation
+	switch method {
+	case "GET":
+		o = &item.Get
+	case "PUT":
+		o = &item.Put
+	case "POST":
+		o = &item.Post
+	case "DELETE":
+		o = &item.Delete
+	case "OPTIONS":
+		o = &item.Options
+	case "HEAD":
+		o = &item.Head
+	case "PATCH":
+		o = &item.Patch
+	default:
+		return false
+	}
+	if *o != nil {
+		return false
+	}
+
+	*o = op
+	g.doc.Paths[path] = item
+	return true
+}
+
+// addRPC describes the endpoints without http annotations as calls to the rpc handler
+func (g *generator) addRPC() {
+	g.add(RPCPath, "POST", &Operation{
+		OperationID: g.id("rpc"),
+		Summary:     "Call a service endpoint",
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(&Schema{OneOf: g.rpc})},
+		Responses:   responses(&Schema{OneOf: unique(g.rsp)}),
+	})
+}
+
+// id returns a unique operation id
+func (g *generator) id(id string) string {
+	g.ids[id]++
+	if n := g.ids[id]; n > 1 {
+		return fmt.Sprintf("%s_%d", id, n)
+	}
+	return id
+}
+
+var pathVar = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// template converts a path template e.g /v1/{name=messages/*} to /v1/{name}
+func template(p string) (string, []string) {
+	var vars []string
+	path := pathVar.ReplaceAllStringFunc(p, func(s string) string {
+		name := pathVar.FindStringSubmatch(s)[1]
+		vars = append(vars, name)
+		return "{" + name + "}"
+	})
+	return path, vars
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+func responses(s *Schema) map[string]*Response {
+	return map[string]*Response{
+		"200": {Description: "OK", Content: jsonContent(s)},
+	}
+}
+
+// unique removes repeated references
+func unique(s []*Schema) []*Schema {
+	seen := make(map[string]bool)
+	var out []*Schema
+	for _, v := range s {
+		if len(v.Ref) > 0 {
+			if seen[v.Ref] {
+				continue
+			}
+			seen[v.Ref] = true
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+var invalidName = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// schemas converts the values of a service, adding messages to the components
+type schemas struct {
+	service    string
+	messages   map[string]*registry.Value
+	components map[string]*Schema
+}
+
+// ref returns the schema of a value, referencing the component of a message
+func (s *schemas) ref(v *registry.Value) *Schema {
+	if v == nil {
+		return &Schema{Type: "object"}
+	}
+
+	if rutil.IsRepeated(v.Type) {
+		elem := &registry.Value{Name: v.Name, Type: rutil.ElemType(v.Type), Values: v.Values}
+		return &Schema{Type: "array", Items: s.ref(elem)}
+	}
+
+	if t, f := rutil.JSONType(v.Type); len(t) > 0 {
+		return &Schema{Type: t, Format: f}
+	}
+
+	m, ok := s.messages[v.Type]
+	if !ok {
+		if len(v.Type) == 0 || strings.HasPrefix(v.Type, "map[") {
+			return &Schema{Type: "object", AdditionalProperties: &Schema{}}
+		}
+		// an enum or a message too deep to be described
+		return &Schema{Title: v.Type}
+	}
+
+	// messages of services are named apart as types like Request are common
+	name := invalidName.ReplaceAllString(s.service+"."+v.Type, "_")
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := s.components[name]; ok {
+		return ref
+	}
+
+	c := &Schema{Title: v.Type, Type: "object", Properties: make(map[string]*Schema)}
+	// added before the fields so recursive messages reference it
+	s.components[name] = c
+	for _, f := range m.Values {
+		c.Properties[f.Name] = s.ref(f)
+	}
+
+	return ref
+}
+
+// field returns the schema of the dot separated field of a value, a string if it isn't found
+func (s *schemas) field(v *registry.Value, path string) *Schema {
+	if f := rutil.Field(v, path); f != nil {
+		return s.ref(f)
+	}
+	return &Schema{Type: "string"}
+}
+
+// query returns the scalar fields of a value, except those named, as query parameters
+func (s *schemas) query(v *registry.Value, except []string) []*Parameter {
+	if v == nil {
+		return nil
+	}
+
+	var params []*Parameter
+
+	for _, f := range v.Values {
+		var skip bool
+		for _, e := range except {
+			if strings.EqualFold(f.Name, e) || strings.HasPrefix(strings.ToLower(e), strings.ToLower(f.Name)+".") {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		if t, _ := rutil.JSONType(rutil.ElemType(f.Type)); len(t) == 0 {
+			continue
+		}
+		params = append(params, &Parameter{Name: f.Name, In: "query", Schema: s.ref(f)})
+	}
+
+	return params
+}
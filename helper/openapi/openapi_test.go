@@ -0,0 +1,147 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/micro/micro/v3/service/registry"
+)
+
+func testServices() []*registry.Service {
+	request := &registry.Value{Name: "Request", Type: "Request", Values: []*registry.Value{
+		{Name: "id", Type: "string"},
+		{Name: "limit", Type: "int32"},
+		{Name: "tags", Type: "[]string"},
+		{Name: "user", Type: "User", Values: []*registry.Value{
+			{Name: "name", Type: "string"},
+		}},
+	}}
+	response := &registry.Value{Name: "Response", Type: "Response", Values: []*registry.Value{
+		{Name: "users", Type: "[]User"},
+		{Name: "labels", Type: ""},
+	}}
+
+	return []*registry.Service{
+		{
+			Name:    "users",
+			Version: "latest",
+			Endpoints: []*registry.Endpoint{
+				{
+					Name:     "Users.Read",
+					Request:  request,
+					Response: response,
+					Metadata: map[string]string{
+						"endpoint": "Users.Read",
+						"method":   "GET",
+						"path":     "/v1/users/{id=*}",
+						"handler":  "rpc",
+					},
+				},
+				{
+					Name:     "Users.Update",
+					Request:  request,
+					Response: response,
+					Metadata: map[string]string{
+						"endpoint": "Users.Update",
+						"method":   "PATCH",
+						"path":     "/v1/users/{id}",
+						"body":     "user",
+						"handler":  "rpc",
+					},
+				},
+				{Name: "Users.Call", Request: request, Response: response},
+			},
+		},
+		{
+			Name:      "other",
+			Version:   "latest",
+			Endpoints: []*registry.Endpoint{{Name: "Other.Call", Request: request, Response: response}},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(&Info{Title: "test", Version: "latest"}, testServices())
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	read := doc.Paths["/v1/users/{id}"].Get
+	if read == nil {
+		t.Fatal("Expected GET /v1/users/{id}")
+	}
+	if read.RequestBody != nil {
+		t.Fatal("Expected no body for GET")
+	}
+
+	var params []string
+	for _, p := range read.Parameters {
+		params = append(params, p.In+":"+p.Name)
+	}
+	// messages can't be query parameters
+	if len(params) != 3 || params[0] != "path:id" || params[1] != "query:limit" || params[2] != "query:tags" {
+		t.Fatalf("Unexpected parameters %v", params)
+	}
+
+	update := doc.Paths["/v1/users/{id}"].Patch
+	if update == nil || update.RequestBody == nil {
+		t.Fatal("Expected PATCH /v1/users/{id} with a body")
+	}
+	if ref := update.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/users.User" {
+		t.Fatalf("Expected the user field as body got %q", ref)
+	}
+
+	// services without http annotations are rpc calls
+	rpc := doc.Paths[RPCPath].Post
+	if rpc == nil {
+		t.Fatal("Expected POST /rpc")
+	}
+	calls := rpc.RequestBody.Content["application/json"].Schema.OneOf
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 rpc calls got %d", len(calls))
+	}
+	// in order of service name
+	if calls[0].Properties["service"].Enum[0] != "other" || calls[1].Properties["endpoint"].Enum[0] != "Users.Call" {
+		t.Fatalf("Unexpected rpc calls %+v %+v", calls[0].Properties, calls[1].Properties)
+	}
+
+	// messages are named by service
+	for _, name := range []string{"users.Request", "users.Response", "users.User", "other.Request"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Fatalf("Expected component %s", name)
+		}
+	}
+
+	rsp := doc.Components.Schemas["users.Response"]
+	if items := rsp.Properties["users"].Items; items == nil || items.Ref != "#/components/schemas/users.User" {
+		t.Fatalf("Expected repeated users to reference the user got %+v", items)
+	}
+	if labels := rsp.Properties["labels"]; labels.Type != "object" || labels.AdditionalProperties == nil {
+		t.Fatalf("Expected labels to be a map got %+v", labels)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	testData := []struct {
+		template string
+		path     string
+		vars     []string
+	}{
+		{"/foo", "/foo", nil},
+		{"/v1/{name=messages/*}", "/v1/{name}", []string{"name"}},
+		{"/v1/{parent}/items/{item.id}", "/v1/{parent}/items/{item.id}", []string{"parent", "item.id"}},
+	}
+
+	for _, d := range testData {
+		path, vars := template(d.template)
+		if path != d.path || len(vars) != len(d.vars) {
+			t.Fatalf("Expected %s %v got %s %v", d.path, d.vars, path, vars)
+		}
+		for i := range vars {
+			if vars[i] != d.vars[i] {
+				t.Fatalf("Expected %v got %v", d.vars, vars)
+			}
+		}
+	}
+}
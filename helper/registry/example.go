@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// Example returns an indented json document of the value with zero values
+// for its fields in declared order, repeated fields have a single element
+func Example(v *registry.Value) string {
+	if v == nil || len(v.Values) == 0 {
+		return "{}"
+	}
+
+	b := new(bytes.Buffer)
+	writeExample(b, v)
+
+	out := new(bytes.Buffer)
+	if err := json.Indent(out, b.Bytes(), "", "  "); err != nil {
+		return "{}"
+	}
+	return out.String()
+}
+
+func writeExample(b *bytes.Buffer, v *registry.Value) {
+	if IsRepeated(v.Type) {
+		b.WriteString("[")
+		// an element of unknown type can't be described
+		if et := ElemType(v.Type); len(v.Values) > 0 || isScalar(et) {
+			writeZero(b, et, v.Values)
+		}
+		b.WriteString("]")
+		return
+	}
+	writeZero(b, v.Type, v.Values)
+}
+
+// writeZero writes the zero value of a type, messages are written field by field
+func writeZero(b *bytes.Buffer, typ string, fields []*registry.Value) {
+	if len(fields) > 0 {
+		b.WriteString("{")
+		for i, f := range fields {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			k, _ := json.Marshal(f.Name)
+			b.Write(k)
+			b.WriteString(":")
+			writeExample(b, f)
+		}
+		b.WriteString("}")
+		return
+	}
+
+	switch {
+	case !isScalar(typ):
+		// an enum or a message too deep to be described
+		b.WriteString("null")
+	case typ == "bool":
+		b.WriteString("false")
+	case IsNumber(typ):
+		b.WriteString("0")
+	case typ == "string", typ == "bytes", typ == "[]byte", typ == "[]uint8":
+		b.WriteString(`""`)
+	default:
+		b.WriteString("{}")
+	}
+}
+
+// isScalar reports whether the type has a zero value without knowing its fields,
+// maps count as they have no type name
+func isScalar(typ string) bool {
+	switch typ {
+	case "bool", "string", "bytes", "[]byte", "[]uint8":
+		return true
+	}
+	return IsNumber(typ) || len(typ) == 0 || strings.HasPrefix(typ, "map[")
+}
@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/micro/micro/v3/service/registry"
+)
+
+func TestExample(t *testing.T) {
+	v := &registry.Value{
+		Name: "Request",
+		Type: "Request",
+		Values: []*registry.Value{
+			{Name: "name", Type: "string"},
+			{Name: "age", Type: "int32"},
+			{Name: "admin", Type: "bool"},
+			{Name: "tags", Type: "[]string"},
+			{Name: "labels", Type: ""},
+			{Name: "status", Type: "Status"},
+			{Name: "address", Type: "Address", Values: []*registry.Value{
+				{Name: "city", Type: "string"},
+				{Name: "lines", Type: "[]string"},
+			}},
+			{Name: "friends", Type: "[]Friend"},
+		},
+	}
+
+	expected := `{"name":"","age":0,"admin":false,"tags":[""],"labels":{},"status":null,` +
+		`"address":{"city":"","lines":[""]},"friends":[]}`
+
+	var doc interface{}
+	s := Example(v)
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("Expected valid json got %v: %s", err, s)
+	}
+
+	b, _ := json.Marshal(doc)
+	var want interface{}
+	json.Unmarshal([]byte(expected), &want)
+	w, _ := json.Marshal(want)
+	if string(b) != string(w) {
+		t.Fatalf("Expected %s got %s", w, b)
+	}
+
+	// fields keep their declared order
+	if s[:14] != "{\n  \"name\": \"\"" {
+		t.Fatalf("Expected name first got %s", s)
+	}
+
+	if s := Example(nil); s != "{}" {
+		t.Fatalf("Expected {} got %s", s)
+	}
+}
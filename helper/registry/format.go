@@ -56,3 +56,118 @@ func reverse(s []string) {
 		s[i], s[j] = s[j], s[i]
 	}
 }
+
+// SchemaDialect is the JSON Schema draft of the generated schemas
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema, an empty schema is any value
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// Schema returns the JSON Schema of a value, its messages are defined in $defs
+func Schema(v *registry.Value) *JSONSchema {
+	if v == nil {
+		return &JSONSchema{Schema: SchemaDialect, Type: "object"}
+	}
+
+	w := &schemaWalker{messages: Messages(v), defs: make(map[string]*JSONSchema)}
+
+	s := w.schema(v)
+	s.Schema = SchemaDialect
+	if len(w.defs) > 0 {
+		s.Defs = w.defs
+	}
+
+	return s
+}
+
+// Messages returns the messages of the values by type, preferring the most
+// complete as the registry truncates deeply nested values
+func Messages(values ...*registry.Value) map[string]*registry.Value {
+	messages := make(map[string]*registry.Value)
+	for _, v := range values {
+		collectMessages(v, messages)
+	}
+	return messages
+}
+
+func collectMessages(v *registry.Value, messages map[string]*registry.Value) {
+	if v == nil || len(v.Values) == 0 {
+		return
+	}
+	typ := ElemType(v.Type)
+	if m, ok := messages[typ]; !ok || len(v.Values) > len(m.Values) {
+		messages[typ] = v
+	}
+	for _, f := range v.Values {
+		collectMessages(f, messages)
+	}
+}
+
+type schemaWalker struct {
+	messages map[string]*registry.Value
+	defs     map[string]*JSONSchema
+}
+
+func (w *schemaWalker) schema(v *registry.Value) *JSONSchema {
+	if IsRepeated(v.Type) {
+		elem := &registry.Value{Name: v.Name, Type: ElemType(v.Type), Values: v.Values}
+		return &JSONSchema{Type: "array", Items: w.schema(elem)}
+	}
+
+	if t, f := JSONType(v.Type); len(t) > 0 {
+		s := &JSONSchema{Type: t, Format: f}
+		switch f {
+		case "int64":
+			// 64 bit integers are encoded as strings by protobuf
+			s.Type = []string{"integer", "string"}
+		case "byte":
+			s.Format = ""
+			s.ContentEncoding = "base64"
+		}
+		return s
+	}
+
+	if len(v.Type) == 0 {
+		// maps have no type name
+		return &JSONSchema{Type: "object", AdditionalProperties: &JSONSchema{}}
+	}
+
+	if strings.HasPrefix(v.Type, "map[") {
+		s := &JSONSchema{Type: "object", AdditionalProperties: &JSONSchema{}}
+		if i := strings.Index(v.Type, "]"); i > 0 {
+			s.AdditionalProperties = w.schema(&registry.Value{Type: v.Type[i+1:]})
+		}
+		return s
+	}
+
+	m, ok := w.messages[v.Type]
+	if !ok {
+		// an enum or a message too deep to be described
+		return &JSONSchema{Title: v.Type}
+	}
+
+	ref := &JSONSchema{Ref: "#/$defs/" + v.Type}
+	if _, ok := w.defs[v.Type]; ok {
+		return ref
+	}
+
+	s := &JSONSchema{Title: v.Type, Type: "object", Properties: make(map[string]*JSONSchema)}
+	// defined before the fields so recursive messages reference it
+	w.defs[v.Type] = s
+	for _, f := range m.Values {
+		s.Properties[f.Name] = w.schema(f)
+	}
+
+	return ref
+}
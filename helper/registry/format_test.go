@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/micro/micro/v3/service/registry"
+)
+
+func TestSchema(t *testing.T) {
+	v := &registry.Value{
+		Name: "Request",
+		Type: "Request",
+		Values: []*registry.Value{
+			{Name: "name", Type: "string"},
+			{Name: "count", Type: "int64"},
+			{Name: "size", Type: "uint32"},
+			{Name: "crc", Type: "fixed32"},
+			{Name: "data", Type: "[]byte"},
+			{Name: "scores", Type: "[]float64"},
+			{Name: "labels", Type: "map[string]string"},
+			{Name: "status", Type: "Status"},
+			{Name: "owner", Type: "User", Values: []*registry.Value{
+				{Name: "name", Type: "string"},
+			}},
+			{Name: "members", Type: "[]User"},
+		},
+	}
+
+	b, err := json.Marshal(Schema(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$ref": "#/$defs/Request",
+		"$defs": {
+			"Request": {
+				"title": "Request",
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"count": {"type": ["integer", "string"], "format": "int64"},
+					"size": {"type": "integer", "format": "uint32"},
+					"crc": {"type": "integer", "format": "uint32"},
+					"data": {"type": "string", "contentEncoding": "base64"},
+					"scores": {"type": "array", "items": {"type": "number", "format": "double"}},
+					"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+					"status": {"title": "Status"},
+					"owner": {"$ref": "#/$defs/User"},
+					"members": {"type": "array", "items": {"$ref": "#/$defs/User"}}
+				}
+			},
+			"User": {
+				"title": "User",
+				"type": "object",
+				"properties": {"name": {"type": "string"}}
+			}
+		}
+	}`
+
+	var got, want interface{}
+	json.Unmarshal(b, &got)
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	g, _ := json.Marshal(got)
+	w, _ := json.Marshal(want)
+	if string(g) != string(w) {
+		t.Fatalf("Expected %s got %s", w, g)
+	}
+}
@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"strings"
+
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// FindEndpoint returns the named endpoint from the first service version which has it
+func FindEndpoint(services []*registry.Service, name string) *registry.Endpoint {
+	for _, s := range services {
+		for _, ep := range s.Endpoints {
+			if ep.Name == name {
+				return ep
+			}
+		}
+	}
+	return nil
+}
+
+// Field walks a dot separated field path through a value, matching names case insensitively
+func Field(v *registry.Value, path string) *registry.Value {
+	if v == nil {
+		return nil
+	}
+
+	for _, name := range strings.Split(path, ".") {
+		var next *registry.Value
+		for _, f := range v.Values {
+			if strings.EqualFold(f.Name, name) {
+				next = f
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		v = next
+	}
+
+	return v
+}
+
+// IsRepeated reports whether the type is a slice e.g []string
+func IsRepeated(typ string) bool {
+	return strings.HasPrefix(typ, "[]") && typ != "[]byte" && typ != "[]uint8"
+}
+
+// ElemType returns the element type of a repeated type
+func ElemType(typ string) string {
+	if IsRepeated(typ) {
+		return strings.TrimPrefix(typ, "[]")
+	}
+	return typ
+}
+
+// IsNumber reports whether the type is one of the numeric scalars
+func IsNumber(typ string) bool {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "double", "float",
+		"sint32", "sint64", "fixed32", "fixed64", "sfixed32", "sfixed64":
+		return true
+	}
+	return false
+}
+
+// JSONType returns the json type and format of a scalar type, the type is empty if it isn't a scalar
+func JSONType(typ string) (string, string) {
+	switch typ {
+	case "bool":
+		return "boolean", ""
+	case "string":
+		return "string", ""
+	case "bytes", "[]byte", "[]uint8":
+		return "string", "byte"
+	case "int", "int8", "int16", "int32", "sint32", "sfixed32", "uint8", "uint16":
+		return "integer", "int32"
+	case "uint32", "fixed32":
+		return "integer", "uint32"
+	case "int64", "sint64", "sfixed64", "uint", "uint64", "fixed64":
+		return "integer", "int64"
+	case "float", "float32":
+		return "number", "float"
+	case "float64", "double":
+		return "number", "double"
+	}
+	return "", ""
+}
@@ -0,0 +1,99 @@
+// Package header is a resolver which uses request headers to determine the service and endpoint
+// to route to. Requests without the service header are resolved by the parent resolver provided in New.
+package header
+
+import (
+	"net/http"
+
+	"github.com/micro-community/micro-webui/resolver"
+)
+
+const (
+	// DefaultServiceHeader is the header holding the service name
+	DefaultServiceHeader = "Micro-Service"
+	// DefaultEndpointHeader is the header holding the endpoint name
+	DefaultEndpointHeader = "Micro-Endpoint"
+)
+
+type Options struct {
+	// ServiceHeader is the header the service name is read from
+	ServiceHeader string
+	// EndpointHeader is the optional header the endpoint name is read from
+	EndpointHeader string
+	// Resolver options e.g the service prefix
+	Resolver resolver.Options
+}
+
+type Option func(o *Options)
+
+// ServiceHeader sets the header the service name is read from
+func ServiceHeader(h string) Option {
+	return func(o *Options) {
+		o.ServiceHeader = h
+	}
+}
+
+// EndpointHeader sets the header the endpoint name is read from, empty to ignore the endpoint
+func EndpointHeader(h string) Option {
+	return func(o *Options) {
+		o.EndpointHeader = h
+	}
+}
+
+// ResolverOptions sets the options of the resolver e.g the service prefix
+func ResolverOptions(opts ...resolver.Option) Option {
+	return func(o *Options) {
+		o.Resolver = resolver.NewOptions(opts...)
+	}
+}
+
+func NewResolver(parent resolver.Resolver, opts ...Option) resolver.Resolver {
+	options := Options{
+		ServiceHeader:  DefaultServiceHeader,
+		EndpointHeader: DefaultEndpointHeader,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &Resolver{options, parent}
+}
+
+type Resolver struct {
+	opts Options
+	resolver.Resolver
+}
+
+func (r *Resolver) Resolve(req *http.Request, opts ...resolver.ResolveOption) (*resolver.Endpoint, error) {
+	name := req.Header.Get(r.opts.ServiceHeader)
+	if len(name) == 0 {
+		if r.Resolver == nil {
+			return nil, resolver.ErrNotFound
+		}
+		return r.Resolver.Resolve(req, opts...)
+	}
+
+	options := resolver.NewResolveOptions(opts...)
+
+	// services are named within the prefix as by the path resolvers
+	if len(r.opts.Resolver.ServicePrefix) > 0 {
+		name = r.opts.Resolver.ServicePrefix + "." + name
+	}
+
+	var endpoint string
+	if len(r.opts.EndpointHeader) > 0 {
+		endpoint = req.Header.Get(r.opts.EndpointHeader)
+	}
+
+	return &resolver.Endpoint{
+		Name:     name,
+		Endpoint: endpoint,
+		Host:     req.Host,
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Domain:   options.Domain,
+	}, nil
+}
+
+func (r *Resolver) String() string {
+	return "header"
+}
@@ -0,0 +1,83 @@
+package header
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/resolver/vpath"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Headers  map[string]string
+		Opts     []Option
+		Service  string
+		Endpoint string
+	}{
+		{
+			Name:     "Service and endpoint",
+			Headers:  map[string]string{"Micro-Service": "micro.greeter", "Micro-Endpoint": "Say.Hello"},
+			Service:  "micro.greeter",
+			Endpoint: "Say.Hello",
+		},
+		{
+			Name:    "Service only",
+			Headers: map[string]string{"Micro-Service": "micro.greeter"},
+			Service: "micro.greeter",
+		},
+		{
+			Name:     "Custom headers",
+			Headers:  map[string]string{"X-Service": "micro.greeter", "X-Endpoint": "Say.Hello", "Micro-Endpoint": "Say.Bye"},
+			Opts:     []Option{ServiceHeader("X-Service"), EndpointHeader("X-Endpoint")},
+			Service:  "micro.greeter",
+			Endpoint: "Say.Hello",
+		},
+		{
+			Name:    "Endpoint header disabled",
+			Headers: map[string]string{"Micro-Service": "micro.greeter", "Micro-Endpoint": "Say.Hello"},
+			Opts:    []Option{EndpointHeader("")},
+			Service: "micro.greeter",
+		},
+		{
+			Name:    "Service prefix",
+			Headers: map[string]string{"Micro-Service": "greeter"},
+			Opts:    []Option{ResolverOptions(resolver.WithServicePrefix("micro"))},
+			Service: "micro.greeter",
+		},
+		{
+			// services outside the namespace can't be named
+			Name:    "Service outside the prefix",
+			Headers: map[string]string{"Micro-Service": "go.micro.auth"},
+			Opts:    []Option{ResolverOptions(resolver.WithServicePrefix("micro"))},
+			Service: "micro.go.micro.auth",
+		},
+		{
+			Name:    "Fallback to parent",
+			Headers: map[string]string{"Micro-Endpoint": "Say.Hello"},
+			Service: "foo",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/foo/bar", nil)
+			for k, v := range tc.Headers {
+				req.Header.Set(k, v)
+			}
+
+			r := NewResolver(vpath.NewResolver(), tc.Opts...)
+			result, err := r.Resolve(req, resolver.Domain("tenant"))
+			assert.Nil(t, err, "Expecting no error to be returned")
+			assert.Equal(t, tc.Service, result.Name)
+			assert.Equal(t, tc.Endpoint, result.Endpoint)
+			assert.Equal(t, "tenant", result.Domain)
+		})
+	}
+
+	// no parent to fall back to
+	_, err := NewResolver(nil).Resolve(httptest.NewRequest("GET", "/foo", nil))
+	assert.Equal(t, resolver.ErrNotFound, err)
+}
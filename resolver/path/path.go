@@ -43,6 +43,7 @@ func (r *Resolver) Resolve(req *http.Request, opts ...resolver.ResolveOption) (*
 		Host:   req.Host,
 		Method: req.Method,
 		Path:   req.URL.Path,
+		Prefix: "/" + parts[0],
 		Domain: options.Domain,
 	}, nil
 }
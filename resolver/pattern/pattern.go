@@ -0,0 +1,350 @@
+// Package pattern is a resolver configured with ordered rules. Each rule matches the request
+// host and path, capturing named values which build the service, endpoint and domain from
+// templates e.g {namespace}.{svc}. Requests no rule matches are resolved by the parent resolver.
+package pattern
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/router"
+	"gopkg.in/yaml.v2"
+)
+
+// placeholder is a named value in a template e.g {svc}
+var placeholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Rule maps requests to a service
+type Rule struct {
+	// Host is an optional regexp the request host must match, named groups are captured
+	Host string `json:"host" yaml:"host"`
+	// Path is a google.api path template e.g /api/{svc}/{method}
+	Path string `json:"path" yaml:"path"`
+	// Regexp is a regexp the path must match instead of a template, named groups are captured
+	Regexp string `json:"regexp" yaml:"regexp"`
+	// Service is the template of the service name e.g {namespace}.{svc}, the
+	// service prefix of the resolver is added unless the name starts with it
+	Service string `json:"service" yaml:"service"`
+	// Endpoint is the optional template of the endpoint e.g Greeter.{method}
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	// Domain is the optional template of the registry domain
+	Domain string `json:"domain" yaml:"domain"`
+
+	host *regexp.Regexp
+	path router.Pattern
+	re   *regexp.Regexp
+	// segments is how many leading path segments name the service, zero if
+	// the path doesn't name it or the number varies e.g after a ** wildcard
+	segments int
+	// groups of the regexp which name the service
+	groups []int
+}
+
+// File is the format of a rules file
+type File struct {
+	Rules []*Rule `json:"rules" yaml:"rules"`
+}
+
+// ParseFile decodes and compiles a yaml or json rules file, json is used for a .json extension
+func ParseFile(name string, b []byte) ([]*Rule, error) {
+	f := new(File)
+
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(b, f)
+	} else {
+		err = yaml.UnmarshalStrict(b, f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", name, err)
+	}
+
+	if err := Compile(f.Rules); err != nil {
+		return nil, err
+	}
+
+	return f.Rules, nil
+}
+
+// Compile validates the rules returning every error found
+func Compile(rules []*Rule) error {
+	var errs []string
+
+	for i, rule := range rules {
+		if err := rule.Compile(); err != nil {
+			errs = append(errs, fmt.Sprintf("rule %d: %v", i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid rules: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// Compile validates the rule and compiles its matchers
+func (r *Rule) Compile() error {
+	if len(r.Service) == 0 {
+		return fmt.Errorf("service required")
+	}
+	if (len(r.Path) == 0) == (len(r.Regexp) == 0) {
+		return fmt.Errorf("one of path or regexp required")
+	}
+
+	names := make(map[string]bool)
+
+	if len(r.Host) > 0 {
+		re, err := regexp.Compile(r.Host)
+		if err != nil {
+			return fmt.Errorf("invalid host: %v", err)
+		}
+		r.host = re
+		for _, n := range re.SubexpNames() {
+			names[n] = true
+		}
+	}
+
+	if len(r.Path) > 0 {
+		rule, err := router.Parse(r.Path)
+		if err != nil {
+			return fmt.Errorf("invalid path: %v", err)
+		}
+		tpl := rule.Compile()
+		p, err := router.NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, tpl.Verb)
+		if err != nil {
+			return fmt.Errorf("invalid path: %v", err)
+		}
+		r.path = p
+		for _, f := range tpl.Fields {
+			names[f] = true
+		}
+		r.segments = serviceSegments(r.Path, r.Service)
+	} else {
+		re, err := regexp.Compile(r.Regexp)
+		if err != nil {
+			return fmt.Errorf("invalid regexp: %v", err)
+		}
+		r.re = re
+		used := placeholders(r.Service)
+		for i, n := range re.SubexpNames() {
+			names[n] = true
+			if len(n) > 0 && used[n] {
+				r.groups = append(r.groups, i)
+			}
+		}
+	}
+
+	// every placeholder must be captured
+	for _, tpl := range []string{r.Service, r.Endpoint, r.Domain} {
+		for _, m := range placeholder.FindAllStringSubmatch(tpl, -1) {
+			if !names[m[1]] {
+				return fmt.Errorf("%s is not captured by the host or path", m[0])
+			}
+		}
+	}
+
+	return nil
+}
+
+// match returns the values captured from the request and the path naming the
+// service, or false if it doesn't match
+func (r *Rule) match(req *http.Request) (map[string]string, string, bool) {
+	vars := make(map[string]string)
+
+	if r.host != nil {
+		m := r.host.FindStringSubmatch(hostname(req))
+		if m == nil {
+			return nil, "", false
+		}
+		capture(r.host, m, vars)
+	}
+
+	if r.re != nil {
+		m := r.re.FindStringSubmatchIndex(req.URL.Path)
+		if m == nil {
+			return nil, "", false
+		}
+		var end int
+		for i, n := range r.re.SubexpNames() {
+			if len(n) > 0 && m[2*i] >= 0 {
+				vars[n] = req.URL.Path[m[2*i]:m[2*i+1]]
+			}
+		}
+		for _, g := range r.groups {
+			if m[2*g+1] > end {
+				end = m[2*g+1]
+			}
+		}
+		// only whole segments are a prefix
+		var prefix string
+		if end > 0 && (end == len(req.URL.Path) || req.URL.Path[end] == '/') {
+			prefix = req.URL.Path[:end]
+		}
+		return vars, prefix, true
+	}
+
+	components, verb := router.SplitPath(req.URL.Path)
+	matches, err := r.path.Match(components, verb)
+	if err != nil {
+		return nil, "", false
+	}
+	for k, v := range matches {
+		vars[k] = v
+	}
+
+	var prefix string
+	if r.segments > 0 && r.segments <= len(components) {
+		prefix = "/" + strings.Join(components[:r.segments], "/")
+	}
+
+	return vars, prefix, true
+}
+
+func NewResolver(parent resolver.Resolver, rules []*Rule, opts ...resolver.Option) (resolver.Resolver, error) {
+	if err := Compile(rules); err != nil {
+		return nil, err
+	}
+	return &Resolver{resolver.NewOptions(opts...), rules, parent}, nil
+}
+
+type Resolver struct {
+	opts  resolver.Options
+	rules []*Rule
+	resolver.Resolver
+}
+
+func (r *Resolver) Resolve(req *http.Request, opts ...resolver.ResolveOption) (*resolver.Endpoint, error) {
+	options := resolver.NewResolveOptions(opts...)
+
+	// the first matching rule wins
+	for _, rule := range r.rules {
+		vars, prefix, ok := rule.match(req)
+		if !ok {
+			continue
+		}
+
+		name := expand(rule.Service, vars)
+		if len(name) == 0 {
+			continue
+		}
+		// services are named within the prefix as by the path resolvers
+		if p := r.opts.ServicePrefix; len(p) > 0 && !strings.HasPrefix(name, p+".") {
+			name = p + "." + name
+		}
+
+		domain := options.Domain
+		if d := expand(rule.Domain, vars); len(d) > 0 {
+			domain = d
+		}
+
+		return &resolver.Endpoint{
+			Name:     name,
+			Endpoint: expand(rule.Endpoint, vars),
+			Host:     req.Host,
+			Method:   req.Method,
+			Path:     req.URL.Path,
+			Prefix:   prefix,
+			Domain:   domain,
+		}, nil
+	}
+
+	if r.Resolver == nil {
+		return nil, resolver.ErrNotFound
+	}
+
+	return r.Resolver.Resolve(req, opts...)
+}
+
+func (r *Resolver) String() string {
+	return "pattern"
+}
+
+// expand replaces the placeholders in the template with the captured values
+func expand(tpl string, vars map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(tpl, func(m string) string {
+		return vars[m[1:len(m)-1]]
+	})
+}
+
+// placeholders returns the names of the placeholders in the template
+func placeholders(tpl string) map[string]bool {
+	names := make(map[string]bool)
+	for _, m := range placeholder.FindAllStringSubmatch(tpl, -1) {
+		names[m[1]] = true
+	}
+	return names
+}
+
+// serviceSegments counts the leading segments of the path template up to the
+// last one capturing a placeholder of the service template
+func serviceSegments(path, service string) int {
+	used := placeholders(service)
+
+	var segments, count, depth int
+	var seg strings.Builder
+
+	// split the template on the slashes outside variables
+	var parts []string
+	for _, c := range strings.TrimPrefix(path, "/") {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == '/' && depth == 0:
+			parts = append(parts, seg.String())
+			seg.Reset()
+			continue
+		}
+		seg.WriteRune(c)
+	}
+	parts = append(parts, seg.String())
+
+	for _, part := range parts {
+		if strings.Contains(part, "**") {
+			// the number of segments varies from here on
+			return segments
+		}
+
+		var name string
+		if m := placeholder.FindStringSubmatch(part); m != nil {
+			name = m[1]
+			if idx := strings.IndexRune(name, '='); idx >= 0 {
+				// a variable over many segments e.g {name=books/*}
+				count += strings.Count(name[idx:], "/")
+				name = name[:idx]
+			}
+		}
+		count++
+
+		if used[name] {
+			segments = count
+		}
+	}
+
+	return segments
+}
+
+// capture adds the named groups of a regexp match to the values
+func capture(re *regexp.Regexp, m []string, vars map[string]string) {
+	for i, n := range re.SubexpNames() {
+		if len(n) > 0 {
+			vars[n] = m[i]
+		}
+	}
+}
+
+// hostname is the request host without the port
+func hostname(req *http.Request) string {
+	if h, _, err := net.SplitHostPort(req.Host); err == nil {
+		return h
+	}
+	return req.Host
+}
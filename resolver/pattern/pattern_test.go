@@ -0,0 +1,190 @@
+package pattern
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/resolver/vpath"
+	"github.com/stretchr/testify/assert"
+)
+
+const rulesYAML = `
+rules:
+  - host: ^(?P<namespace>[a-z]+)\.example\.com$
+    path: /api/{svc}/{method}
+    service: "{namespace}.{svc}"
+    endpoint: "Handler.{method}"
+    domain: "{namespace}"
+  - path: /api/{svc}/{method}
+    service: micro.{svc}
+    endpoint: "{svc}.{method}"
+  - regexp: ^/legacy/(?P<svc>[a-z]+)(/.*)?$
+    service: legacy.{svc}
+`
+
+func TestParseFile(t *testing.T) {
+	rules, err := ParseFile("rules.yaml", []byte(rulesYAML))
+	assert.Nil(t, err)
+	assert.Len(t, rules, 3)
+
+	rules, err = ParseFile("rules.json", []byte(`{"rules":[{"path":"/{svc}","service":"{svc}"}]}`))
+	assert.Nil(t, err)
+	assert.Len(t, rules, 1)
+
+	tt := []struct {
+		Name  string
+		Rules string
+	}{
+		{Name: "Unknown field", Rules: "rules:\n  - pth: /{svc}\n    service: '{svc}'\n"},
+		{Name: "No service", Rules: "rules:\n  - path: /{svc}\n"},
+		{Name: "No path or regexp", Rules: "rules:\n  - service: foo\n"},
+		{Name: "Path and regexp", Rules: "rules:\n  - path: /foo\n    regexp: ^/foo$\n    service: foo\n"},
+		{Name: "Invalid path", Rules: "rules:\n  - path: /{svc\n    service: foo\n"},
+		{Name: "Invalid regexp", Rules: "rules:\n  - regexp: ^/(foo\n    service: foo\n"},
+		{Name: "Invalid host", Rules: "rules:\n  - host: (foo\n    path: /foo\n    service: foo\n"},
+		{Name: "Uncaptured placeholder", Rules: "rules:\n  - path: /{svc}\n    service: '{namespace}.{svc}'\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := ParseFile("rules.yaml", []byte(tc.Rules))
+			assert.NotNil(t, err, "Expecting an error to be returned")
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	rules, err := ParseFile("rules.yaml", []byte(rulesYAML))
+	assert.Nil(t, err)
+
+	r, err := NewResolver(vpath.NewResolver(), rules)
+	assert.Nil(t, err)
+
+	tt := []struct {
+		Name     string
+		Host     string
+		Path     string
+		Service  string
+		Endpoint string
+		Domain   string
+		Prefix   string
+	}{
+		{
+			Name:     "Host and template",
+			Host:     "acme.example.com:8080",
+			Path:     "/api/greeter/Hello",
+			Service:  "acme.greeter",
+			Endpoint: "Handler.Hello",
+			Domain:   "acme",
+			Prefix:   "/api/greeter",
+		},
+		{
+			Name:     "Template",
+			Host:     "localhost",
+			Path:     "/api/greeter/Hello",
+			Service:  "micro.greeter",
+			Endpoint: "greeter.Hello",
+			Domain:   "micro",
+			Prefix:   "/api/greeter",
+		},
+		{
+			Name:    "Regexp",
+			Host:    "localhost",
+			Path:    "/legacy/users/1",
+			Service: "legacy.users",
+			Domain:  "micro",
+			Prefix:  "/legacy/users",
+		},
+		{
+			Name:    "Fallback to parent",
+			Host:    "localhost",
+			Path:    "/foo/bar",
+			Service: "foo",
+			Domain:  "micro",
+			Prefix:  "/foo",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.Path, nil)
+			req.Host = tc.Host
+
+			result, err := r.Resolve(req)
+			assert.Nil(t, err, "Expecting no error to be returned")
+			assert.Equal(t, tc.Service, result.Name)
+			assert.Equal(t, tc.Endpoint, result.Endpoint)
+			assert.Equal(t, tc.Domain, result.Domain)
+			assert.Equal(t, tc.Prefix, result.Prefix)
+		})
+	}
+
+	// no parent to fall back to
+	r, err = NewResolver(nil, rules)
+	assert.Nil(t, err)
+	_, err = r.Resolve(httptest.NewRequest("GET", "/foo", nil))
+	assert.Equal(t, resolver.ErrNotFound, err)
+
+	// invalid rules are rejected
+	_, err = NewResolver(nil, []*Rule{{Path: "/foo"}})
+	assert.NotNil(t, err)
+}
+
+func TestResolveServicePrefix(t *testing.T) {
+	rules, err := ParseFile("rules.yaml", []byte(rulesYAML))
+	assert.Nil(t, err)
+
+	rules = append(rules, &Rule{Regexp: `^/(?P<svc>[a-z]+)-v1/`, Service: "{svc}"})
+
+	opts := []resolver.Option{resolver.WithServicePrefix("micro")}
+	r, err := NewResolver(vpath.NewResolver(opts...), rules, opts...)
+	assert.Nil(t, err)
+
+	tt := []struct {
+		Name    string
+		Host    string
+		Path    string
+		Service string
+		Prefix  string
+	}{
+		{Name: "Host and template", Host: "acme.example.com", Path: "/api/greeter/Hello", Service: "micro.acme.greeter", Prefix: "/api/greeter"},
+		// already within the prefix
+		{Name: "Template", Host: "localhost", Path: "/api/greeter/Hello", Service: "micro.greeter", Prefix: "/api/greeter"},
+		{Name: "Regexp", Host: "localhost", Path: "/legacy/users", Service: "micro.legacy.users", Prefix: "/legacy/users"},
+		// the service doesn't end on a segment
+		{Name: "Partial segment", Host: "localhost", Path: "/users-v1/list", Service: "micro.users"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.Path, nil)
+			req.Host = tc.Host
+
+			result, err := r.Resolve(req)
+			assert.Nil(t, err, "Expecting no error to be returned")
+			assert.Equal(t, tc.Service, result.Name)
+			assert.Equal(t, tc.Prefix, result.Prefix)
+		})
+	}
+}
+
+func TestServiceSegments(t *testing.T) {
+	tt := []struct {
+		Path     string
+		Service  string
+		Segments int
+	}{
+		{"/api/{svc}/{method}", "{svc}", 2},
+		{"/{ns}/api/{svc}", "{ns}.{svc}", 3},
+		{"/v1/{name=shelves/*}/books", "{name}", 3},
+		{"/v1/{svc}/{path=**}", "{svc}", 2},
+		// unknown after a wildcard
+		{"/v1/{path=**}/{svc}", "{svc}", 0},
+		{"/v1/{method}", "greeter", 0},
+	}
+
+	for _, tc := range tt {
+		assert.Equal(t, tc.Segments, serviceSegments(tc.Path, tc.Service), tc.Path)
+	}
+}
@@ -18,6 +18,7 @@
 package resolver
 
 import (
+	"context"
 	"errors"
 	"net/http"
 )
@@ -37,12 +38,30 @@ type Resolver interface {
 type Endpoint struct {
 	// e.g greeter
 	Name string
+	// RPC endpoint e.g Say.Hello, empty when not resolved from the request
+	Endpoint string
 	// HTTP Host e.g example.com
 	Host string
 	// HTTP Methods e.g GET, POST
 	Method string
 	// HTTP Path e.g /greeter.
 	Path string
+	// Prefix of the path naming the service e.g /v1/greeter, empty when
+	// the service isn't named by the path
+	Prefix string
 	// Domain endpoint exists within
 	Domain string
 }
+
+type endpointKey struct{}
+
+// NewContext returns a context carrying the endpoint a request was resolved to
+func NewContext(ctx context.Context, e *Endpoint) context.Context {
+	return context.WithValue(ctx, endpointKey{}, e)
+}
+
+// FromContext returns the endpoint the request was resolved to
+func FromContext(ctx context.Context) (*Endpoint, bool) {
+	e, ok := ctx.Value(endpointKey{}).(*Endpoint)
+	return e, ok
+}
@@ -0,0 +1,94 @@
+// Package rpc resolves the service and endpoint from the http path as the micro api gateway does
+// e.g /greeter/say/hello => service: greeter, endpoint: Say.Hello
+package rpc
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/micro-community/micro-webui/resolver"
+)
+
+func NewResolver(opts ...resolver.Option) resolver.Resolver {
+	return &Resolver{opts: resolver.NewOptions(opts...)}
+}
+
+type Resolver struct {
+	opts resolver.Options
+}
+
+var (
+	versionRe = regexp.MustCompile("^v[0-9]+$")
+)
+
+func (r *Resolver) Resolve(req *http.Request, opts ...resolver.ResolveOption) (*resolver.Endpoint, error) {
+	if req.URL.Path == "/" || len(req.URL.Path) == 0 {
+		return nil, resolver.ErrNotFound
+	}
+
+	options := resolver.NewResolveOptions(opts...)
+
+	name, endpoint := route(req.URL.Path)
+
+	return &resolver.Endpoint{
+		Name:     r.withPrefix(name...),
+		Endpoint: endpoint,
+		Host:     req.Host,
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Prefix:   "/" + strings.Join(name, "/"),
+		Domain:   options.Domain,
+	}, nil
+}
+
+func (r *Resolver) String() string {
+	return "rpc"
+}
+
+// withPrefix transforms "foo" into "go.micro.api.foo"
+func (r *Resolver) withPrefix(parts ...string) string {
+	p := r.opts.ServicePrefix
+	if len(p) > 0 {
+		parts = append([]string{p}, parts...)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// route splits the path into the service name parts and the endpoint
+func route(p string) ([]string, string) {
+	p = strings.TrimPrefix(path.Clean(p), "/")
+	parts := strings.Split(p, "/")
+
+	switch {
+	// /foo => service: foo, endpoint: Foo.Call
+	case len(parts) == 1:
+		return parts, endpointName(parts[0], "call")
+	// /v1/foo => service: v1.foo, endpoint: Foo.Call
+	case len(parts) == 2 && versionRe.MatchString(parts[0]):
+		return parts, endpointName(parts[1], "call")
+	// /foo/bar => service: foo, endpoint: Foo.Bar
+	case len(parts) == 2:
+		return parts[:1], endpointName(parts...)
+	// /v1/foo/bar => service: v1.foo, endpoint: Foo.Bar
+	case len(parts) == 3 && versionRe.MatchString(parts[0]):
+		return parts[:2], endpointName(parts[1:]...)
+	}
+
+	// /foo/bar/baz => service: foo, endpoint: Bar.Baz
+	// /v1/foo/bar/baz => service: v1.foo, endpoint: Bar.Baz
+	return parts[:len(parts)-2], endpointName(parts[len(parts)-2:]...)
+}
+
+// endpointName camel cases the parts e.g ["say", "hello-world"] => Say.HelloWorld
+func endpointName(parts ...string) string {
+	names := make([]string, len(parts))
+	for i, part := range parts {
+		for _, word := range strings.Split(part, "-") {
+			names[i] += strings.Title(word)
+		}
+	}
+	return strings.Join(names, ".")
+}
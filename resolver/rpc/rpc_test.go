@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	tt := []struct {
+		Path     string
+		Service  string
+		Endpoint string
+		Prefix   string
+	}{
+		{Path: "/greeter", Service: "micro.greeter", Endpoint: "Greeter.Call", Prefix: "/greeter"},
+		{Path: "/greeter/hello", Service: "micro.greeter", Endpoint: "Greeter.Hello", Prefix: "/greeter"},
+		{Path: "/greeter/say/hello", Service: "micro.greeter", Endpoint: "Say.Hello", Prefix: "/greeter"},
+		{Path: "/greeter/say/hello-world/", Service: "micro.greeter", Endpoint: "Say.HelloWorld", Prefix: "/greeter"},
+		{Path: "/foo/bar/say/hello", Service: "micro.foo.bar", Endpoint: "Say.Hello", Prefix: "/foo/bar"},
+		{Path: "/v1/greeter", Service: "micro.v1.greeter", Endpoint: "Greeter.Call", Prefix: "/v1/greeter"},
+		{Path: "/v1/greeter/hello", Service: "micro.v1.greeter", Endpoint: "Greeter.Hello", Prefix: "/v1/greeter"},
+		{Path: "/v1/greeter/say/hello", Service: "micro.v1.greeter", Endpoint: "Say.Hello", Prefix: "/v1/greeter"},
+	}
+
+	r := NewResolver(resolver.WithServicePrefix("micro"))
+
+	for _, tc := range tt {
+		t.Run(tc.Path, func(t *testing.T) {
+			result, err := r.Resolve(httptest.NewRequest("POST", tc.Path, nil))
+			assert.Nil(t, err, "Expecting no error to be returned")
+			assert.Equal(t, tc.Service, result.Name)
+			assert.Equal(t, tc.Endpoint, result.Endpoint)
+			assert.Equal(t, tc.Prefix, result.Prefix)
+			assert.Equal(t, "POST", result.Method)
+		})
+	}
+
+	_, err := r.Resolve(httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, resolver.ErrNotFound, err)
+}
@@ -52,6 +52,7 @@ func (r *Resolver) Resolve(req *http.Request, opts ...resolver.ResolveOption) (*
 			Host:   req.Host,
 			Method: req.Method,
 			Path:   req.URL.Path,
+			Prefix: "/" + parts[0],
 			Domain: options.Domain,
 		}, nil
 	}
@@ -63,6 +64,7 @@ func (r *Resolver) Resolve(req *http.Request, opts ...resolver.ResolveOption) (*
 			Host:   req.Host,
 			Method: req.Method,
 			Path:   req.URL.Path,
+			Prefix: "/" + strings.Join(parts[0:2], "/"),
 			Domain: options.Domain,
 		}, nil
 	}
@@ -72,6 +74,7 @@ func (r *Resolver) Resolve(req *http.Request, opts ...resolver.ResolveOption) (*
 		Host:   req.Host,
 		Method: req.Method,
 		Path:   req.URL.Path,
+		Prefix: "/" + parts[0],
 		Domain: options.Domain,
 	}, nil
 }
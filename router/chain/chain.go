@@ -0,0 +1,177 @@
+// Package chain provides a router which consults a list of routers in order
+package chain
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro/micro/v3/service/api"
+)
+
+const (
+	// Header is the response header naming the layer which routed a request
+	Header = "X-Micro-Route-Layer"
+	// Fallback is the layer of requests routed by the resolver
+	Fallback = "resolver"
+)
+
+// Layer is a named router in the chain
+type Layer struct {
+	Name   string
+	Router router.Router
+}
+
+type layerKey struct{}
+
+// NewContext returns a context annotated with the layer which routed the request
+func NewContext(ctx context.Context, layer string) context.Context {
+	return context.WithValue(ctx, layerKey{}, layer)
+}
+
+// FromContext returns the layer which routed the request
+func FromContext(ctx context.Context) (string, bool) {
+	layer, ok := ctx.Value(layerKey{}).(string)
+	return layer, ok
+}
+
+// chainRouter tries the endpoints of each layer in order, the last layer
+// resolves requests which none of the layers have an endpoint for
+type chainRouter struct {
+	layers []Layer
+}
+
+func (r *chainRouter) Options() router.Options {
+	return r.layers[len(r.layers)-1].Router.Options()
+}
+
+func (r *chainRouter) Close() error {
+	var err error
+	for _, l := range r.layers {
+		if cerr := l.Router.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Register adds the endpoint to the first layer where it overrides the others
+func (r *chainRouter) Register(ep *api.Endpoint, opts ...router.RegisterOption) error {
+	return r.layers[0].Router.Register(ep, opts...)
+}
+
+func (r *chainRouter) Deregister(ep *api.Endpoint) error {
+	return r.layers[0].Router.Deregister(ep)
+}
+
+func (r *chainRouter) Endpoint(req *http.Request) (*api.Service, *http.Request, error) {
+	var err error
+
+	for _, l := range r.layers {
+		// only the request returned by the layer which matched carries
+		// what it matched, a layer failing after matching leaves nothing
+		var svc *api.Service
+		var rreq *http.Request
+		svc, rreq, err = l.Router.Endpoint(req)
+		if err != nil {
+			continue
+		}
+		return svc, rreq.WithContext(NewContext(rreq.Context(), l.Name)), nil
+	}
+
+	return nil, nil, err
+}
+
+func (r *chainRouter) Route(req *http.Request) (*api.Service, *http.Request, error) {
+	// try get an endpoint
+	svc, rreq, err := r.Endpoint(req)
+	if err == nil {
+		return svc, rreq, nil
+	}
+
+	// fallback to the resolver of the last layer, its endpoints were
+	// already tried above
+	last := r.layers[len(r.layers)-1].Router
+	if res, ok := last.(router.Resolver); ok {
+		svc, rreq, err = res.Resolve(req)
+	} else {
+		svc, rreq, err = last.Route(req)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return svc, rreq.WithContext(NewContext(rreq.Context(), Fallback)), nil
+}
+
+func (r *chainRouter) Routes() []*router.Route {
+	var routes []*router.Route
+
+	for _, l := range r.layers {
+		for _, route := range l.Router.Routes() {
+			route.Layer = l.Name
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
+
+// Explain combines the explanations of the layers, endpoints matched in a
+// layer after the first match are shadowed
+func (r *chainRouter) Explain(req *http.Request) *router.Explanation {
+	exp := &router.Explanation{
+		Method: req.Method,
+		Host:   req.URL.Host,
+		Path:   req.URL.Path,
+	}
+
+	var last *router.Explanation
+
+	for _, l := range r.layers {
+		ex, ok := l.Router.(router.Explainer)
+		if !ok {
+			continue
+		}
+
+		last = ex.Explain(req)
+		if len(last.Domain) > 0 {
+			exp.Domain = last.Domain
+		}
+
+		for _, c := range last.Candidates {
+			c.Layer = l.Name
+			if len(c.Rejected) > 0 {
+				continue
+			}
+			if exp.Match == nil {
+				exp.Match = c
+			} else {
+				c.Rejected = router.StageShadowed
+			}
+		}
+
+		exp.Candidates = append(exp.Candidates, last.Candidates...)
+	}
+
+	if exp.Match == nil && last != nil {
+		exp.Fallback = last.Fallback
+		exp.Error = last.Error
+	}
+
+	return exp
+}
+
+// Stats returns the stats of the first layer which syncs from the registry
+func (r *chainRouter) Stats() *router.Stats {
+	for _, l := range r.layers {
+		if m, ok := l.Router.(router.Monitor); ok {
+			return m.Stats()
+		}
+	}
+	return nil
+}
+
+// NewRouter returns a router which tries each layer in order, at least one layer is required
+func NewRouter(layers ...Layer) router.Router {
+	return &chainRouter{layers: layers}
+}
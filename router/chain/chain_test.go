@@ -0,0 +1,208 @@
+package chain
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/resolver/vpath"
+	"github.com/micro-community/micro-webui/router"
+	regRouter "github.com/micro-community/micro-webui/router/registry"
+	"github.com/micro-community/micro-webui/router/static"
+	"github.com/micro-community/micro-webui/router/transform"
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/context/metadata"
+	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/registry/memory"
+)
+
+func TestChain(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(&registry.Service{
+		Name:  "greeter",
+		Nodes: []*registry.Node{{Id: "greeter-1", Address: "127.0.0.1:8080"}},
+	})
+
+	pinned := static.NewRouter(router.WithRegistry(reg))
+	pinned.Register(&api.Endpoint{
+		Name:    "greeter.Say.Pinned",
+		Handler: "rpc",
+		Method:  []string{"GET"},
+		Path:    []string{"/v1/greeter"},
+	})
+
+	overrides := static.NewRouter(router.WithRegistry(reg))
+	overrides.Register(&api.Endpoint{
+		Name:    "greeter.Say.Hello",
+		Handler: "rpc",
+		Method:  []string{"GET"},
+		Path:    []string{"/v1/greeter", "/v1/hello"},
+	})
+
+	discovered := regRouter.NewRouter(
+		router.WithRegistry(reg),
+		router.WithResolver(vpath.NewResolver(resolver.WithHandler("meta"))),
+	)
+
+	rt := NewRouter(
+		Layer{Name: "pinned", Router: pinned},
+		Layer{Name: "static", Router: overrides},
+		Layer{Name: "registry", Router: discovered},
+	)
+	defer rt.Close()
+
+	testData := []struct {
+		path     string
+		endpoint string
+		layer    string
+	}{
+		{"/v1/greeter", "Say.Pinned", "pinned"},
+		{"/v1/hello", "Say.Hello", "static"},
+		// the resolver picks the endpoint
+		{"/greeter/say/hello", "", Fallback},
+	}
+
+	for _, d := range testData {
+		svc, req, err := rt.Route(httptest.NewRequest("GET", d.path, nil))
+		if err != nil {
+			t.Fatalf("%s: %v", d.path, err)
+		}
+		if svc.Name != "greeter" {
+			t.Fatalf("%s: expected service greeter got %s", d.path, svc.Name)
+		}
+		if len(d.endpoint) > 0 && svc.Endpoint.Name != d.endpoint {
+			t.Fatalf("%s: expected endpoint %s got %s", d.path, d.endpoint, svc.Endpoint.Name)
+		}
+		if layer, _ := FromContext(req.Context()); layer != d.layer {
+			t.Fatalf("%s: expected layer %s got %s", d.path, d.layer, layer)
+		}
+	}
+
+	routes := rt.Routes()
+	if len(routes) != 2 || routes[0].Layer != "pinned" || routes[1].Layer != "static" {
+		t.Fatalf("Unexpected routes %+v", routes)
+	}
+
+	exp := rt.(router.Explainer).Explain(httptest.NewRequest("GET", "/v1/greeter", nil))
+	if exp.Match == nil || exp.Match.Layer != "pinned" {
+		t.Fatalf("Expected match in pinned layer got %+v", exp.Match)
+	}
+	if len(exp.Candidates) != 2 || exp.Candidates[1].Rejected != router.StageShadowed {
+		t.Fatalf("Expected static layer to be shadowed %+v", exp.Candidates)
+	}
+}
+
+// resolvingRouter has no endpoints and resolves every request to greeter
+type resolvingRouter struct {
+	router.Router
+	endpoints int
+}
+
+func (r *resolvingRouter) Endpoint(req *http.Request) (*api.Service, *http.Request, error) {
+	r.endpoints++
+	return nil, nil, errors.New("not found")
+}
+
+func (r *resolvingRouter) Route(req *http.Request) (*api.Service, *http.Request, error) {
+	if svc, rreq, err := r.Endpoint(req); err == nil {
+		return svc, rreq, nil
+	}
+	return r.Resolve(req)
+}
+
+func (r *resolvingRouter) Resolve(req *http.Request) (*api.Service, *http.Request, error) {
+	return &api.Service{Name: "greeter", Endpoint: &api.Endpoint{}}, req, nil
+}
+
+func TestChainFallback(t *testing.T) {
+	last := &resolvingRouter{}
+	rt := NewRouter(
+		Layer{Name: "static", Router: static.NewRouter(router.WithRegistry(memory.NewRegistry()))},
+		Layer{Name: "registry", Router: last},
+	)
+
+	svc, req, err := rt.Route(httptest.NewRequest("GET", "/greeter/hello", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Name != "greeter" {
+		t.Fatalf("Expected service greeter got %s", svc.Name)
+	}
+	if layer, _ := FromContext(req.Context()); layer != Fallback {
+		t.Fatalf("Expected layer %s got %s", Fallback, layer)
+	}
+	// the endpoints of the last layer are only matched once
+	if last.endpoints != 1 {
+		t.Fatalf("Expected 1 endpoint match got %d", last.endpoints)
+	}
+}
+
+func TestChainFailedLayer(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(&registry.Service{
+		Name: "greeter",
+		Endpoints: []*registry.Endpoint{{
+			Name: "Say.Hello",
+			Metadata: map[string]string{
+				"endpoint": "Say.Hello",
+				"method":   "GET",
+				"path":     "/v1/greeter",
+				"handler":  "rpc",
+			},
+		}},
+		Nodes: []*registry.Node{{Id: "greeter-1", Address: "127.0.0.1:8080"}},
+	})
+
+	// matches the request but its service isn't registered
+	overrides := static.NewRouter(router.WithRegistry(reg))
+	rules := &transform.Rules{Request: []*transform.Rule{{Type: transform.StripPrefix, Value: "/v1"}}}
+	if err := overrides.Register(&api.Endpoint{
+		Name:    "missing.Say.Hello",
+		Handler: "rpc",
+		Method:  []string{"GET"},
+		Path:    []string{"/v1/{name}"},
+	}, router.RegisterRules(rules)); err != nil {
+		t.Fatal(err)
+	}
+
+	discovered := regRouter.NewRouter(
+		router.WithRegistry(reg),
+		router.WithResolver(vpath.NewResolver(resolver.WithHandler("meta"))),
+	)
+
+	rt := NewRouter(
+		Layer{Name: "static", Router: overrides},
+		Layer{Name: "registry", Router: discovered},
+	)
+	defer rt.Close()
+
+	// wait for the registry layer to sync
+	for i := 0; i < 100 && len(discovered.Routes()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	svc, req, err := rt.Route(httptest.NewRequest("GET", "/v1/greeter", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Name != "greeter" || svc.Endpoint.Name != "Say.Hello" {
+		t.Fatalf("Expected greeter Say.Hello got %s %s", svc.Name, svc.Endpoint.Name)
+	}
+	if layer, _ := FromContext(req.Context()); layer != "registry" {
+		t.Fatalf("Expected layer registry got %s", layer)
+	}
+	if r := transform.FromContext(req.Context()); r != nil {
+		t.Fatalf("Expected no transform rules got %+v", r)
+	}
+	if md, ok := metadata.FromContext(req.Context()); ok {
+		if v, ok := md.Get("x-api-field-name"); ok {
+			t.Fatalf("Expected no path variables of the static route got %s", v)
+		}
+	}
+	if vars, ok := router.VarsFromContext(req.Context()); ok && len(vars.Fields["name"]) > 0 {
+		t.Fatalf("Expected no path variables of the static route got %+v", vars.Fields)
+	}
+}
@@ -0,0 +1,73 @@
+package router
+
+import (
+	"net/http"
+)
+
+// Stages at which an endpoint can fail to match a request
+const (
+	StageMethod = "method"
+	StageHost   = "host"
+	StageGPath  = "gpath"
+	StagePCRE   = "pcre"
+	// StagePredicate is a header, query, cookie or client predicate
+	StagePredicate = "predicate"
+	// StageShadowed is an endpoint which matched after an earlier one
+	StageShadowed = "shadowed"
+)
+
+// Explainer is implemented by routers which can describe how they route a request
+type Explainer interface {
+	// Explain runs the routing logic for the request without routing it
+	Explain(r *http.Request) *Explanation
+}
+
+// Explanation describes a routing decision
+type Explanation struct {
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	// Domain is the registry domain the routes were matched in
+	Domain string `json:"domain,omitempty"`
+	// Candidates in the order they are tried
+	Candidates []*Candidate `json:"candidates"`
+	// Match is the candidate the request was routed to
+	Match *Candidate `json:"match,omitempty"`
+	// Fallback is the resolver result used when no candidate matched
+	Fallback *Fallback `json:"fallback,omitempty"`
+	// Error is the error the router would return
+	Error string `json:"error,omitempty"`
+}
+
+// Candidate is an endpoint considered for a request
+type Candidate struct {
+	Service  string   `json:"service"`
+	Endpoint string   `json:"endpoint"`
+	Handler  string   `json:"handler"`
+	Method   []string `json:"method"`
+	Host     []string `json:"host,omitempty"`
+	Path     []string `json:"path"`
+	// Layer is the router the endpoint belongs to in a chain
+	Layer string `json:"layer,omitempty"`
+	// Domain is the registry domain of the endpoint, empty for routes in every domain
+	Domain string `json:"domain,omitempty"`
+	// Indexed is whether the route index returned the endpoint
+	Indexed bool `json:"indexed"`
+	// Rejected is the stage the endpoint failed at, empty for a match
+	Rejected string `json:"rejected,omitempty"`
+	// Pattern is the path template or regexp which matched
+	Pattern string `json:"pattern,omitempty"`
+	// Vars are the captured path variables
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// Fallback is the endpoint resolved when no route matches
+type Fallback struct {
+	Resolver string `json:"resolver"`
+	Service  string `json:"service"`
+	Endpoint string `json:"endpoint"`
+	Handler  string `json:"handler"`
+	Domain   string `json:"domain,omitempty"`
+	Nodes    int    `json:"nodes"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,193 @@
+package router
+
+import (
+	"strings"
+)
+
+// Index is an immutable lookup table of route keys. Routes are keyed by
+// method and then by the literal prefix of their path templates in a radix
+// tree, so a lookup only returns the routes which could match a request.
+// Routes continuing with a wildcard or variable hang off the node where
+// their literal prefix ends and routes without one e.g pcre paths off the root.
+type Index struct {
+	methods map[string]*node
+}
+
+// node is a radix tree node, prefixes always end on a path separator
+type node struct {
+	prefix   string
+	children []*node
+	keys     []string
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{
+		methods: make(map[string]*node),
+	}
+}
+
+// Insert adds the route key for the method under the literal path prefix
+func (i *Index) Insert(method string, prefix []string, key string) {
+	root, ok := i.methods[method]
+	if !ok {
+		root = &node{prefix: "/"}
+		i.methods[method] = root
+	}
+	root.insert(indexPath(prefix), key)
+}
+
+// InsertPatterns adds the route key for each method under the literal prefix
+// of each path template, and under the root when the route has pcre paths
+// as those are candidates for every request
+func (i *Index) InsertPatterns(key string, methods []string, patterns []Pattern, pcre bool) {
+	for _, m := range methods {
+		for _, p := range patterns {
+			i.Insert(m, p.LiteralPrefix(), key)
+		}
+		if pcre {
+			i.Insert(m, nil, key)
+		}
+	}
+}
+
+// Lookup returns the route keys which may match the method and path
+// components, the most specific prefix first
+func (i *Index) Lookup(method string, components []string) []string {
+	root, ok := i.methods[method]
+	if !ok {
+		return nil
+	}
+
+	var keys []string
+	var seen map[string]bool
+
+	n := root
+	path := indexPath(components)
+
+	for n != nil {
+		// most specific first, earlier matches are appended after
+		if len(n.keys) > 0 {
+			if seen == nil {
+				seen = make(map[string]bool)
+			}
+			var found []string
+			for _, k := range n.keys {
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				found = append(found, k)
+			}
+			keys = append(found, keys...)
+		}
+
+		path = path[len(n.prefix):]
+		n = n.child(path)
+	}
+
+	return keys
+}
+
+// Len returns the number of route entries across all methods
+func (i *Index) Len() int {
+	var l int
+	for _, n := range i.methods {
+		l += n.len()
+	}
+	return l
+}
+
+// LiteralPrefix returns the literal path components a request must begin with
+func (p Pattern) LiteralPrefix() []string {
+	var prefix []string
+	for _, op := range p.ops {
+		if op.code == OpNop {
+			continue
+		}
+		if op.code != OpLitPush {
+			break
+		}
+		prefix = append(prefix, p.pool[op.operand])
+	}
+	return prefix
+}
+
+func (n *node) insert(path, key string) {
+	for {
+		path = path[len(n.prefix):]
+		if len(path) == 0 {
+			for _, k := range n.keys {
+				if k == key {
+					return
+				}
+			}
+			n.keys = append(n.keys, key)
+			return
+		}
+
+		var next *node
+		for idx, c := range n.children {
+			l := commonPrefix(c.prefix, path)
+			if l == 0 {
+				continue
+			}
+			if l < len(c.prefix) {
+				// split the child on the common prefix
+				split := &node{
+					prefix:   c.prefix[:l],
+					children: []*node{c},
+				}
+				c.prefix = c.prefix[l:]
+				n.children[idx] = split
+				c = split
+			}
+			next = c
+			break
+		}
+
+		if next == nil {
+			n.children = append(n.children, &node{prefix: path, keys: []string{key}})
+			return
+		}
+
+		n = next
+	}
+}
+
+// child returns the child whose prefix begins the path
+func (n *node) child(path string) *node {
+	for _, c := range n.children {
+		if strings.HasPrefix(path, c.prefix) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) len() int {
+	l := len(n.keys)
+	for _, c := range n.children {
+		l += c.len()
+	}
+	return l
+}
+
+// commonPrefix returns the length of the common prefix on segment boundaries
+func commonPrefix(a, b string) int {
+	var l int
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '/' {
+			l = i + 1
+		}
+	}
+	return l
+}
+
+// indexPath joins path components into a tree key e.g /v1/books/
+func indexPath(components []string) string {
+	if len(components) == 0 {
+		return "/"
+	}
+	return "/" + strings.Join(components, "/") + "/"
+}
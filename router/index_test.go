@@ -0,0 +1,63 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	idx := NewIndex()
+
+	for _, route := range []struct {
+		method string
+		tmpl   string
+		key    string
+	}{
+		{"GET", "/v1/books", "books.list"},
+		{"GET", "/v1/books/{id}", "books.get"},
+		{"GET", "/v1/{name=books/*}:publish", "books.publish"},
+		{"GET", "/v1/bookshelves/{id}", "shelves.get"},
+		{"GET", "/{path=**}", "catchall"},
+		{"POST", "/v1/books", "books.create"},
+	} {
+		rule, err := Parse(route.tmpl)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed with %v", route.tmpl, err)
+		}
+		tpl := rule.Compile()
+		pat, err := NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, tpl.Verb)
+		if err != nil {
+			t.Fatalf("NewPattern(%q) failed with %v", route.tmpl, err)
+		}
+		idx.Insert(route.method, pat.LiteralPrefix(), route.key)
+	}
+
+	// pcre style routes are indexed at the root
+	idx.Insert("GET", nil, "pcre")
+	// duplicates are ignored
+	idx.Insert("GET", []string{"v1", "books"}, "books.list")
+
+	if l := idx.Len(); l != 7 {
+		t.Fatalf("Expected 7 routes got %d", l)
+	}
+
+	for _, spec := range []struct {
+		method string
+		path   string
+		keys   []string
+	}{
+		{"GET", "/v1/books", []string{"books.list", "books.get", "books.publish", "catchall", "pcre"}},
+		{"GET", "/v1/books/1:publish", []string{"books.list", "books.get", "books.publish", "catchall", "pcre"}},
+		{"GET", "/v1/bookshelves/1", []string{"shelves.get", "catchall", "pcre"}},
+		{"GET", "/v2/books", []string{"catchall", "pcre"}},
+		{"POST", "/v1/books", []string{"books.create"}},
+		{"POST", "/v1", nil},
+		{"DELETE", "/v1/books", nil},
+	} {
+		components, _ := SplitPath(spec.path)
+		keys := idx.Lookup(spec.method, components)
+		if !reflect.DeepEqual(keys, spec.keys) {
+			t.Errorf("Lookup(%s %s) = %q; want %q", spec.method, spec.path, keys, spec.keys)
+		}
+	}
+}
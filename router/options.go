@@ -17,8 +17,12 @@
 package router
 
 import (
+	"time"
+
 	"github.com/micro-community/micro-webui/resolver"
 	"github.com/micro-community/micro-webui/resolver/vpath"
+	"github.com/micro-community/micro-webui/router/predicate"
+	"github.com/micro-community/micro-webui/router/transform"
 	"github.com/micro/micro/v3/service/registry"
 	"github.com/micro/micro/v3/service/registry/mdns"
 )
@@ -27,6 +31,17 @@ type Options struct {
 	Handler  string
 	Registry registry.Registry
 	Resolver resolver.Resolver
+	// AllowColonFinalSegments treats a colon in the last path
+	// segment as part of the path when the template has no verb
+	AllowColonFinalSegments bool
+	// RoutesFile is a yaml or json file of static routes
+	RoutesFile string
+	// ResyncInterval is how often the registry router does a full
+	// resync on top of watching, zero uses the default and negative disables it
+	ResyncInterval time.Duration
+	// Domains are the registry domains the registry router watches,
+	// the default domain if empty and every domain for a wildcard
+	Domains []string
 }
 
 type Option func(o *Options)
@@ -67,3 +82,64 @@ func WithResolver(r resolver.Resolver) Option {
 		o.Resolver = r
 	}
 }
+
+// WithAllowColonFinalSegments allows colons in the final path segment of templates without a verb
+func WithAllowColonFinalSegments(b bool) Option {
+	return func(o *Options) {
+		o.AllowColonFinalSegments = b
+	}
+}
+
+// WithRoutesFile loads static routes from the file, reloading it on change
+func WithRoutesFile(path string) Option {
+	return func(o *Options) {
+		o.RoutesFile = path
+	}
+}
+
+// WithResyncInterval sets how often routes are fully resynced from the registry
+func WithResyncInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.ResyncInterval = d
+	}
+}
+
+// WithDomains sets the registry domains to route to, registry.WildcardDomain for all
+func WithDomains(d ...string) Option {
+	return func(o *Options) {
+		o.Domains = d
+	}
+}
+
+// RegisterOptions are the options of an endpoint registered with a router
+type RegisterOptions struct {
+	// Rules transform the matched requests and their responses
+	Rules *transform.Rules
+	// Predicates the request must also satisfy e.g a header or cookie
+	Predicates predicate.Predicates
+}
+
+type RegisterOption func(o *RegisterOptions)
+
+// NewRegisterOptions returns the register options set by opts
+func NewRegisterOptions(opts ...RegisterOption) RegisterOptions {
+	var options RegisterOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
+// RegisterRules sets the transform rules applied to the requests the endpoint matches
+func RegisterRules(r *transform.Rules) RegisterOption {
+	return func(o *RegisterOptions) {
+		o.Rules = r
+	}
+}
+
+// RegisterPredicates restricts the endpoint to requests which satisfy the predicates
+func RegisterPredicates(p predicate.Predicates) RegisterOption {
+	return func(o *RegisterOptions) {
+		o.Predicates = p
+	}
+}
@@ -0,0 +1,164 @@
+// Package predicate provides request predicates routes must satisfy besides method, host and path
+package predicate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// MetadataKey is the endpoint metadata key holding the json encoded predicates
+const MetadataKey = "predicates"
+
+// Predicate types
+const (
+	// Header requires header Name, equal to Value or matching Pattern when set
+	Header = "header"
+	// Query requires query parameter Name, equal to Value or matching Pattern when set
+	Query = "query"
+	// Cookie requires cookie Name, equal to Value or matching Pattern when set
+	Cookie = "cookie"
+	// CIDR requires the client address to be within one of the comma separated networks in Value
+	CIDR = "cidr"
+)
+
+// Predicate is a single condition on a request
+type Predicate struct {
+	Type    string `json:"type" yaml:"type"`
+	Name    string `json:"name,omitempty" yaml:"name"`
+	Value   string `json:"value,omitempty" yaml:"value"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern"`
+
+	re   *regexp.Regexp
+	nets []*net.IPNet
+}
+
+// Predicates are the conditions of a route, all of which must hold
+type Predicates []*Predicate
+
+// Decode parses and compiles json encoded predicates
+func Decode(s string) (Predicates, error) {
+	var p Predicates
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return nil, err
+	}
+	if err := p.Compile(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Compile validates the predicates and compiles any patterns and networks
+func (p Predicates) Compile() error {
+	for _, pred := range p {
+		switch pred.Type {
+		case Header, Query, Cookie:
+			if len(pred.Name) == 0 {
+				return fmt.Errorf("%s requires a name", pred.Type)
+			}
+			if len(pred.Value) > 0 && len(pred.Pattern) > 0 {
+				return fmt.Errorf("%s %s has both a value and pattern", pred.Type, pred.Name)
+			}
+			if len(pred.Pattern) > 0 {
+				re, err := regexp.Compile(pred.Pattern)
+				if err != nil {
+					return err
+				}
+				pred.re = re
+			}
+		case CIDR:
+			pred.nets = nil
+			for _, c := range strings.Split(pred.Value, ",") {
+				_, n, err := net.ParseCIDR(strings.TrimSpace(c))
+				if err != nil {
+					return err
+				}
+				pred.nets = append(pred.nets, n)
+			}
+		default:
+			return fmt.Errorf("unknown predicate %q", pred.Type)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the request satisfies every predicate.
+// It's safe to call on nil predicates.
+func (p Predicates) Match(req *http.Request) bool {
+	for _, pred := range p {
+		if !pred.Match(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether the request satisfies the predicate
+func (p *Predicate) Match(req *http.Request) bool {
+	switch p.Type {
+	case Header:
+		vals, ok := req.Header[http.CanonicalHeaderKey(p.Name)]
+		return ok && p.matchAny(vals)
+	case Query:
+		vals, ok := req.URL.Query()[p.Name]
+		return ok && p.matchAny(vals)
+	case Cookie:
+		c, err := req.Cookie(p.Name)
+		return err == nil && p.matchAny([]string{c.Value})
+	case CIDR:
+		ip := clientIP(req)
+		if ip == nil {
+			return false
+		}
+		for _, n := range p.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String describes the predicate
+func (p *Predicate) String() string {
+	switch {
+	case p.Type == CIDR:
+		return fmt.Sprintf("%s in %s", p.Type, p.Value)
+	case len(p.Pattern) > 0:
+		return fmt.Sprintf("%s %s ~ %s", p.Type, p.Name, p.Pattern)
+	case len(p.Value) > 0:
+		return fmt.Sprintf("%s %s = %s", p.Type, p.Name, p.Value)
+	}
+	return fmt.Sprintf("%s %s", p.Type, p.Name)
+}
+
+// matchAny checks the values against the value or pattern, any value matches when neither is set
+func (p *Predicate) matchAny(vals []string) bool {
+	for _, v := range vals {
+		switch {
+		case p.re != nil:
+			if p.re.MatchString(v) {
+				return true
+			}
+		case len(p.Value) > 0:
+			if v == p.Value {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the address of the peer, forwarding headers can be spoofed so aren't trusted
+func clientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,85 @@
+package predicate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	testData := []struct {
+		predicates string
+		valid      bool
+	}{
+		{`[{"type":"header","name":"X-Version","value":"v2"}]`, true},
+		{`[{"type":"query","name":"beta"},{"type":"cookie","name":"group","pattern":"^b"}]`, true},
+		{`[{"type":"cidr","value":"10.0.0.0/8, 192.168.0.0/16"}]`, true},
+		{`[{"type":"header"}]`, false},
+		{`[{"type":"header","name":"X-Version","value":"v2","pattern":"v2"}]`, false},
+		{`[{"type":"cookie","name":"group","pattern":"("}]`, false},
+		{`[{"type":"cidr","value":"10.0.0.0"}]`, false},
+		{`[{"type":"unknown"}]`, false},
+		{`not json`, false},
+	}
+
+	for _, d := range testData {
+		_, err := Decode(d.predicates)
+		if d.valid && err != nil {
+			t.Errorf("Expected %s to be valid got %v", d.predicates, err)
+		} else if !d.valid && err == nil {
+			t.Errorf("Expected %s to be invalid", d.predicates)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	testData := []struct {
+		name       string
+		predicates string
+		req        func(r *http.Request)
+		match      bool
+	}{
+		{"none", `[]`, func(r *http.Request) {}, true},
+		{"header equals", `[{"type":"header","name":"x-version","value":"v2"}]`,
+			func(r *http.Request) { r.Header.Set("X-Version", "v2") }, true},
+		{"header differs", `[{"type":"header","name":"X-Version","value":"v2"}]`,
+			func(r *http.Request) { r.Header.Set("X-Version", "v1") }, false},
+		{"header missing", `[{"type":"header","name":"X-Version"}]`,
+			func(r *http.Request) {}, false},
+		{"header regex", `[{"type":"header","name":"X-Tenant","pattern":"^acme-"}]`,
+			func(r *http.Request) { r.Header.Set("X-Tenant", "acme-eu") }, true},
+		{"query present", `[{"type":"query","name":"beta"}]`,
+			func(r *http.Request) { r.URL.RawQuery = "beta" }, true},
+		{"query equals", `[{"type":"query","name":"v","value":"2"}]`,
+			func(r *http.Request) { r.URL.RawQuery = "v=1&v=2" }, true},
+		{"cookie", `[{"type":"cookie","name":"group","value":"b"}]`,
+			func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "group", Value: "b"}) }, true},
+		{"cookie missing", `[{"type":"cookie","name":"group","value":"b"}]`,
+			func(r *http.Request) {}, false},
+		{"cidr", `[{"type":"cidr","value":"192.168.0.0/16,10.0.0.0/8"}]`,
+			func(r *http.Request) { r.RemoteAddr = "10.1.2.3:1234" }, true},
+		{"cidr outside", `[{"type":"cidr","value":"10.0.0.0/8"}]`,
+			func(r *http.Request) { r.RemoteAddr = "192.0.2.1:1234" }, false},
+		{"cidr ignores forwarding", `[{"type":"cidr","value":"10.0.0.0/8"}]`,
+			func(r *http.Request) {
+				r.RemoteAddr = "192.0.2.1:1234"
+				r.Header.Set("X-Forwarded-For", "10.1.2.3")
+			}, false},
+		{"all must hold", `[{"type":"header","name":"X-Version","value":"v2"},{"type":"query","name":"beta"}]`,
+			func(r *http.Request) { r.Header.Set("X-Version", "v2") }, false},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			p, err := Decode(d.predicates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/foo", nil)
+			d.req(req)
+			if got := p.Match(req); got != d.match {
+				t.Fatalf("Expected match %v got %v", d.match, got)
+			}
+		})
+	}
+}
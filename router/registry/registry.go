@@ -22,14 +22,17 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
-	"strings"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/micro-community/micro-webui/resolver"
 	"github.com/micro-community/micro-webui/router"
 	util "github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/predicate"
+	"github.com/micro-community/micro-webui/router/transform"
 	"github.com/micro/micro/v3/service/api"
-	"github.com/micro/micro/v3/service/context/metadata"
 	"github.com/micro/micro/v3/service/logger"
 	"github.com/micro/micro/v3/service/registry"
 	"github.com/micro/micro/v3/service/registry/cache"
@@ -40,6 +43,40 @@ type endpoint struct {
 	hostregs []*regexp.Regexp
 	pathregs []util.Pattern
 	pcreregs []*regexp.Regexp
+	rules    *transform.Rules
+	// header, query, cookie and client predicates
+	predicates predicate.Predicates
+}
+
+// table is an immutable snapshot of the routes in a domain, replaced on every rebuild
+type table struct {
+	eps map[string]*api.Service
+	// compiled regexp for host and path
+	ceps map[string]*endpoint
+	// index of endpoint keys by method and path prefix
+	idx *util.Index
+}
+
+// tables are the routes keyed by registry domain
+type tables map[string]*table
+
+// empty is the table of a domain with no routes
+var empty = &table{
+	eps:  make(map[string]*api.Service),
+	ceps: make(map[string]*endpoint),
+	idx:  util.NewIndex(),
+}
+
+// domainResolver is implemented by resolvers which scope requests to a
+// registry domain e.g subdomain
+type domainResolver interface {
+	Domain(req *http.Request) string
+}
+
+// serviceKey names a service in a registry domain
+type serviceKey struct {
+	domain string
+	name   string
 }
 
 // router is the default router
@@ -50,10 +87,72 @@ type registryRouter struct {
 	// registry cache
 	rc cache.Cache
 
-	sync.RWMutex
-	eps map[string]*api.Service
-	// compiled regexp for host and path
-	ceps map[string]*endpoint
+	// serialises resyncs so an older listing never replaces a newer one
+	resyncMtx sync.Mutex
+	// serialises updates to the services and table
+	sync.Mutex
+	// versions of each service by domain as seen by the watcher
+	services map[string]map[string][]*registry.Service
+	// sequence of the last watch event and the event that last changed each service
+	seq     uint64
+	changed map[serviceKey]uint64
+	// the current tables, read without locking
+	tbl atomic.Value
+
+	stats stats
+}
+
+// tables returns the current routes of every domain
+func (r *registryRouter) tables() tables {
+	return r.tbl.Load().(tables)
+}
+
+// table returns the current routes of the domain
+func (r *registryRouter) table(domain string) *table {
+	if t, ok := r.tables()[domain]; ok {
+		return t
+	}
+	return empty
+}
+
+// domains returns the registry domains the router watches
+func (r *registryRouter) domains() []string {
+	if len(r.opts.Domains) == 0 {
+		return []string{registry.DefaultDomain}
+	}
+	for _, d := range r.opts.Domains {
+		if d == registry.WildcardDomain {
+			return []string{d}
+		}
+	}
+	return r.opts.Domains
+}
+
+// domain returns the registry domain the request is scoped to
+func (r *registryRouter) domain(req *http.Request) string {
+	if dr, ok := r.opts.Resolver.(domainResolver); ok {
+		if d := dr.Domain(req); len(d) > 0 {
+			return d
+		}
+	}
+	return registry.DefaultDomain
+}
+
+// domainOf returns the domain of a service seen in the watched domain. A
+// wildcard watch relies on the registry setting it in the metadata.
+func domainOf(s *registry.Service, watched string) string {
+	if watched != registry.WildcardDomain {
+		return watched
+	}
+	if d := s.Metadata["domain"]; len(d) > 0 {
+		return d
+	}
+	for _, n := range s.Nodes {
+		if d := n.Metadata["domain"]; len(d) > 0 {
+			return d
+		}
+	}
+	return registry.DefaultDomain
 }
 
 func (r *registryRouter) isClosed() bool {
@@ -65,184 +164,403 @@ func (r *registryRouter) isClosed() bool {
 	}
 }
 
-// refresh list of api services
+// refresh resyncs all the services periodically in case watch events were missed
 func (r *registryRouter) refresh() {
+	interval := r.opts.ResyncInterval
+	if interval == 0 {
+		interval = DefaultResyncInterval
+	}
+
 	var attempts int
 
 	for {
-		services, err := r.opts.Registry.ListServices()
-		if err != nil {
+		if err := r.resync(); err != nil {
 			attempts++
 			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
-				logger.Errorf("unable to list services: %v", err)
+				logger.Errorf("unable to resync services: %v", err)
+			}
+			if !r.sleep(backoff(attempts)) {
+				return
 			}
-			time.Sleep(time.Duration(attempts) * time.Second)
 			continue
 		}
 
 		attempts = 0
 
-		// for each service, get service and store endpoints
-		for _, s := range services {
-			service, err := r.rc.GetService(s.Name)
-			if err != nil {
-				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
-					logger.Errorf("unable to get service: %v", err)
-				}
-				continue
+		// the watcher keeps us up to date in between
+		if interval < 0 {
+			return
+		}
+		if !r.sleep(interval) {
+			return
+		}
+	}
+}
+
+// resync replaces the routes with a full listing of the watched domains
+func (r *registryRouter) resync() error {
+	r.resyncMtx.Lock()
+	defer r.resyncMtx.Unlock()
+
+	// events applied while listing are newer than what we list
+	r.Lock()
+	seq := r.seq
+	r.Unlock()
+
+	all := make(map[string]map[string][]*registry.Service)
+
+	for _, domain := range r.domains() {
+		if err := r.list(domain, all); err != nil {
+			r.stats.failed()
+			return err
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// keep the services changed by events since we started listing
+	for key, s := range r.changed {
+		if s <= seq {
+			delete(r.changed, key)
+			continue
+		}
+		if versions := r.services[key.domain][key.name]; len(versions) > 0 {
+			if all[key.domain] == nil {
+				all[key.domain] = make(map[string][]*registry.Service)
+			}
+			all[key.domain][key.name] = versions
+		} else if all[key.domain] != nil {
+			delete(all[key.domain], key.name)
+			if len(all[key.domain]) == 0 {
+				delete(all, key.domain)
 			}
-			r.store(service)
 		}
+	}
 
-		// refresh list in 10 minutes... cruft
-		// use registry watching
-		select {
-		case <-time.After(time.Minute * 10):
-		case <-r.exit:
-			return
+	// rebuild what we had and what we've found
+	names := make(map[string]map[string]bool)
+	for _, services := range []map[string]map[string][]*registry.Service{r.services, all} {
+		for domain, versions := range services {
+			if names[domain] == nil {
+				names[domain] = make(map[string]bool, len(versions))
+			}
+			for name := range versions {
+				names[domain][name] = true
+			}
 		}
 	}
+
+	r.services = all
+	for domain, n := range names {
+		r.rebuild(domain, n)
+	}
+	r.stats.resynced()
+
+	return nil
 }
 
-// process watch event
-func (r *registryRouter) process(res *registry.Result) {
+// list adds the services of the domain to all by the domain they're in
+func (r *registryRouter) list(domain string, all map[string]map[string][]*registry.Service) error {
+	services, err := r.opts.Registry.ListServices(registry.ListDomain(domain))
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(services))
+
+	// for each service, get service and store endpoints
+	for _, s := range services {
+		if seen[s.Name] {
+			continue
+		}
+		seen[s.Name] = true
+
+		versions, err := r.opts.Registry.GetService(s.Name, registry.GetDomain(domain))
+		if err == registry.ErrNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		for _, v := range versions {
+			d := domainOf(v, domain)
+			if all[d] == nil {
+				all[d] = make(map[string][]*registry.Service)
+			}
+			all[d][v.Name] = append(all[d][v.Name], v)
+		}
+	}
+
+	return nil
+}
+
+// sleep waits for the duration returning false if the router is closed
+func (r *registryRouter) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.exit:
+		return false
+	}
+}
+
+// process applies a watch event from the watched domain to the service versions
+func (r *registryRouter) process(res *registry.Result, watched string) {
 	// skip these things
 	if res == nil || res.Service == nil {
 		return
 	}
 
-	// get entry from cache
-	service, err := r.rc.GetService(res.Service.Name)
-	if err != nil {
-		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
-			logger.Errorf("unable to get %v service: %v", res.Service.Name, err)
-		}
+	r.Lock()
+	defer r.Unlock()
+
+	domain := domainOf(res.Service, watched)
+	services := r.services[domain]
+	if services == nil {
+		services = make(map[string][]*registry.Service)
+		r.services[domain] = services
+	}
+
+	name := res.Service.Name
+	versions := services[name]
+
+	switch res.Action {
+	case "create", "update":
+		versions = addVersion(versions, res.Service)
+	case "delete":
+		versions = delVersion(versions, res.Service)
+	default:
 		return
 	}
 
+	if len(versions) > 0 {
+		services[name] = versions
+	} else {
+		delete(services, name)
+	}
+	if len(services) == 0 {
+		delete(r.services, domain)
+	}
+
+	r.seq++
+	r.changed[serviceKey{domain, name}] = r.seq
+
 	// update our local endpoints
-	r.store(service)
+	r.rebuild(domain, map[string]bool{name: true})
+	r.stats.event()
 }
 
-// store local endpoint cache
-func (r *registryRouter) store(services []*registry.Service) {
+// rebuild the endpoints of the named services in the domain and swap in the new table
+func (r *registryRouter) rebuild(domain string, names map[string]bool) {
 	// endpoints
 	eps := map[string]*api.Service{}
 
-	// services
-	names := map[string]bool{}
+	// transform rules
+	rules := map[string]*transform.Rules{}
+
+	// predicates
+	predicates := map[string]predicate.Predicates{}
 
 	// create a new endpoint mapping
-	for _, service := range services {
-		// set names we need later
-		names[service.Name] = true
-
-		// map per endpoint
-		for _, sep := range service.Endpoints {
-			// create a key service:endpoint_name
-			key := fmt.Sprintf("%s.%s", service.Name, sep.Name)
-			// decode endpoint
-			end := api.Decode(sep.Metadata)
-			// no endpoint or no name
-			if end == nil || len(end.Name) == 0 {
-				continue
-			}
-			// if we got nothing skip
-			if err := api.Validate(end); err != nil {
-				if logger.V(logger.TraceLevel, logger.DefaultLogger) {
-					logger.Tracef("endpoint validation failed: %v", err)
+	for name := range names {
+		for _, service := range r.services[domain][name] {
+			// map per endpoint
+			for _, sep := range service.Endpoints {
+				// create a key service:endpoint_name
+				key := fmt.Sprintf("%s.%s", service.Name, sep.Name)
+				// decode endpoint
+				end := api.Decode(sep.Metadata)
+				// no endpoint or no name
+				if end == nil || len(end.Name) == 0 {
+					continue
 				}
-				continue
-			}
+				// the body selector isn't decoded by the api package
+				end.Body = sep.Metadata["body"]
 
-			// try get endpoint
-			ep, ok := eps[key]
-			if !ok {
-				ep = &api.Service{Name: service.Name}
-			}
+				// if we got nothing skip
+				if err := api.Validate(end); err != nil {
+					if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+						logger.Tracef("endpoint validation failed: %v", err)
+					}
+					continue
+				}
+
+				// skip endpoints with invalid predicates rather than match everything
+				var preds predicate.Predicates
+				if v, ok := sep.Metadata[predicate.MetadataKey]; ok {
+					p, err := predicate.Decode(v)
+					if err != nil {
+						if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+							logger.Tracef("endpoint have invalid predicates: %v", err)
+						}
+						continue
+					}
+					preds = p
+				}
+
+				// try get endpoint
+				ep, ok := eps[key]
+				if !ok {
+					ep = &api.Service{Name: service.Name}
+				}
+
+				// decode any transform rules
+				if v, ok := sep.Metadata[transform.MetadataKey]; ok {
+					tr, err := transform.Decode(v)
+					if err != nil {
+						if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+							logger.Tracef("endpoint have invalid transform rules: %v", err)
+						}
+					} else {
+						rules[key] = tr
+					}
+				}
 
-			// overwrite the endpoint
-			ep.Endpoint = end
-			// append services
-			ep.Services = append(ep.Services, service)
-			// store it
-			eps[key] = ep
+				predicates[key] = preds
+
+				// overwrite the endpoint
+				ep.Endpoint = end
+				// append services
+				ep.Services = append(ep.Services, service)
+				// store it
+				eps[key] = ep
+			}
 		}
 	}
 
-	r.Lock()
-	defer r.Unlock()
+	old := r.table(domain)
+	t := &table{
+		eps:  make(map[string]*api.Service, len(old.eps)+len(eps)),
+		ceps: make(map[string]*endpoint, len(old.ceps)+len(eps)),
+	}
 
-	// delete any existing eps for services we know
-	for key, service := range r.eps {
-		// skip what we don't care about
-		if !names[service.Name] {
+	// copy the existing eps for services we don't know
+	for key, service := range old.eps {
+		// skip what we're replacing
+		if names[service.Name] {
 			continue
 		}
-
-		// ok we know this thing
-		// delete delete delete
-		delete(r.eps, key)
+		t.eps[key] = service
+		t.ceps[key] = old.ceps[key]
 	}
 
 	// now set the eps we have
 	for name, ep := range eps {
-		r.eps[name] = ep
-		cep := &endpoint{}
+		t.eps[name] = ep
+		t.ceps[name] = r.compile(ep.Endpoint, rules[name], predicates[name])
+	}
 
-		for _, h := range ep.Endpoint.Host {
-			if h == "" || h == "*" {
-				continue
-			}
-			hostreg, err := regexp.CompilePOSIX(h)
-			if err != nil {
-				if logger.V(logger.TraceLevel, logger.DefaultLogger) {
-					logger.Tracef("endpoint have invalid host regexp: %v", err)
-				}
-				continue
+	t.idx = index(t)
+
+	// copy the other domains, dropping this one if it has no routes left
+	cur := r.tables()
+	tbls := make(tables, len(cur)+1)
+	for d, dt := range cur {
+		tbls[d] = dt
+	}
+	if len(t.eps) > 0 {
+		tbls[domain] = t
+	} else {
+		delete(tbls, domain)
+	}
+
+	// swap in the new routes
+	r.tbl.Store(tbls)
+}
+
+// compile the host and path matchers of an endpoint
+func (r *registryRouter) compile(ep *api.Endpoint, rules *transform.Rules, preds predicate.Predicates) *endpoint {
+	cep := &endpoint{rules: rules, predicates: preds}
+
+	for _, h := range ep.Host {
+		if h == "" || h == "*" {
+			continue
+		}
+		hostreg, err := regexp.CompilePOSIX(h)
+		if err != nil {
+			if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+				logger.Tracef("endpoint have invalid host regexp: %v", err)
 			}
-			cep.hostregs = append(cep.hostregs, hostreg)
+			continue
 		}
+		cep.hostregs = append(cep.hostregs, hostreg)
+	}
 
-		for _, p := range ep.Endpoint.Path {
-			var pcreok bool
+	for _, p := range ep.Path {
+		var pcreok bool
 
-			if p[0] == '^' && p[len(p)-1] == '$' {
-				pcrereg, err := regexp.CompilePOSIX(p)
-				if err == nil {
-					cep.pcreregs = append(cep.pcreregs, pcrereg)
-					pcreok = true
-				}
+		if p[0] == '^' && p[len(p)-1] == '$' {
+			pcrereg, err := regexp.CompilePOSIX(p)
+			if err == nil {
+				cep.pcreregs = append(cep.pcreregs, pcrereg)
+				pcreok = true
 			}
+		}
 
-			rule, err := util.Parse(p)
-			if err != nil && !pcreok {
-				if logger.V(logger.TraceLevel, logger.DefaultLogger) {
-					logger.Tracef("endpoint have invalid path pattern: %v", err)
-				}
-				continue
-			} else if err != nil && pcreok {
-				continue
+		rule, err := util.Parse(p)
+		if err != nil && !pcreok {
+			if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+				logger.Tracef("endpoint have invalid path pattern: %v", err)
 			}
+			continue
+		} else if err != nil && pcreok {
+			continue
+		}
 
-			tpl := rule.Compile()
-			pathreg, err := util.NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, "")
-			if err != nil {
-				if logger.V(logger.TraceLevel, logger.DefaultLogger) {
-					logger.Tracef("endpoint have invalid path pattern: %v", err)
-				}
-				continue
+		tpl := rule.Compile()
+		pathreg, err := util.NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, tpl.Verb,
+			util.AssumeColonVerbOpt(!r.opts.AllowColonFinalSegments))
+		if err != nil {
+			if logger.V(logger.TraceLevel, logger.DefaultLogger) {
+				logger.Tracef("endpoint have invalid path pattern: %v", err)
 			}
-			cep.pathregs = append(cep.pathregs, pathreg)
+			continue
 		}
+		cep.pathregs = append(cep.pathregs, pathreg)
+	}
+
+	return cep
+}
 
-		r.ceps[name] = cep
+// index builds the route index of a table
+func index(t *table) *util.Index {
+	keys := make([]string, 0, len(t.eps))
+	for key := range t.eps {
+		keys = append(keys, key)
 	}
+	// stable ordering within the index
+	sort.Strings(keys)
+
+	idx := util.NewIndex()
+
+	for _, key := range keys {
+		cep := t.ceps[key]
+		idx.InsertPatterns(key, t.eps[key].Endpoint.Method, cep.pathregs, len(cep.pcreregs) > 0)
+	}
+
+	return idx
 }
 
-// watch for endpoint changes
+// watch for endpoint changes in each of the domains
 func (r *registryRouter) watch() {
+	var wg sync.WaitGroup
+
+	for _, domain := range r.domains() {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			r.watchDomain(domain)
+		}(domain)
+	}
+
+	wg.Wait()
+}
+
+// watchDomain applies the changes in a domain, reconnecting on error
+func (r *registryRouter) watchDomain(domain string) {
 	var attempts int
+	var connected bool
 
 	for {
 		if r.isClosed() {
@@ -250,16 +568,33 @@ func (r *registryRouter) watch() {
 		}
 
 		// watch for changes
-		w, err := r.opts.Registry.Watch()
+		w, err := r.opts.Registry.Watch(registry.WatchDomain(domain))
 		if err != nil {
 			attempts++
+			r.stats.failed()
 			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
-				logger.Errorf("error watching endpoints: %v", err)
+				logger.Errorf("error watching endpoints in %s: %v", domain, err)
+			}
+			if !r.sleep(backoff(attempts)) {
+				return
 			}
-			time.Sleep(time.Duration(attempts) * time.Second)
 			continue
 		}
 
+		r.stats.connect()
+
+		// we may have missed events while disconnected
+		if connected {
+			go func() {
+				if err := r.resync(); err != nil {
+					if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+						logger.Errorf("unable to resync services: %v", err)
+					}
+				}
+			}()
+		}
+		connected = true
+
 		ch := make(chan bool)
 
 		go func() {
@@ -271,9 +606,6 @@ func (r *registryRouter) watch() {
 			}
 		}()
 
-		// reset if we get here
-		attempts = 0
-
 		for {
 			// process next event
 			res, err := w.Next()
@@ -284,11 +616,42 @@ func (r *registryRouter) watch() {
 				close(ch)
 				break
 			}
-			r.process(res)
+			// reset once the watcher is delivering
+			attempts = 0
+			r.process(res, domain)
+		}
+
+		r.stats.disconnect()
+
+		if r.isClosed() {
+			return
+		}
+
+		attempts++
+		if !r.sleep(backoff(attempts)) {
+			return
 		}
 	}
 }
 
+// Stats returns how up to date the routes are
+func (r *registryRouter) Stats() *router.Stats {
+	st := r.stats.snapshot()
+
+	for _, t := range r.tables() {
+		st.Endpoints += len(t.eps)
+	}
+
+	r.Lock()
+	st.Domains = len(r.services)
+	for _, services := range r.services {
+		st.Services += len(services)
+	}
+	r.Unlock()
+
+	return st
+}
+
 func (r *registryRouter) Options() router.Options {
 	return r.opts
 }
@@ -304,7 +667,7 @@ func (r *registryRouter) Close() error {
 	return nil
 }
 
-func (r *registryRouter) Register(ep *api.Endpoint) error {
+func (r *registryRouter) Register(ep *api.Endpoint, opts ...util.RegisterOption) error {
 	return nil
 }
 
@@ -312,141 +675,228 @@ func (r *registryRouter) Deregister(ep *api.Endpoint) error {
 	return nil
 }
 
-func (r *registryRouter) Endpoint(req *http.Request) (*api.Service, error) {
-	if r.isClosed() {
-		return nil, errors.New("router closed")
+func (r *registryRouter) Routes() []*router.Route {
+	tbls := r.tables()
+
+	domains := make([]string, 0, len(tbls))
+	for domain := range tbls {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	var routes []*router.Route
+
+	for _, domain := range domains {
+		routes = append(routes, domainRoutes(domain, tbls[domain])...)
+	}
+
+	return routes
+}
+
+// domainRoutes lists the routes of a domain
+func domainRoutes(domain string, t *table) []*router.Route {
+	keys := make([]string, 0, len(t.eps))
+	for key := range t.eps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	routes := make([]*router.Route, 0, len(keys))
+
+	for _, key := range keys {
+		e := t.eps[key]
+		route := &router.Route{
+			Service:  e.Name,
+			Domain:   domain,
+			Endpoint: e.Endpoint,
+		}
+
+		seen := make(map[string]bool)
+		for _, s := range e.Services {
+			if seen[s.Version] {
+				continue
+			}
+			seen[s.Version] = true
+			route.Versions = append(route.Versions, s.Version)
+		}
+		sort.Strings(route.Versions)
+
+		if cep, ok := t.ceps[key]; ok {
+			for _, pathreg := range cep.pathregs {
+				route.Templates = append(route.Templates, pathreg.String())
+			}
+			for _, pathreg := range cep.pcreregs {
+				route.Templates = append(route.Templates, pathreg.String())
+			}
+		}
+
+		routes = append(routes, route)
 	}
 
-	r.RLock()
-	defer r.RUnlock()
+	return routes
+}
 
-	var idx int
-	if len(req.URL.Path) > 0 && req.URL.Path != "/" {
-		idx = 1
+func (r *registryRouter) Endpoint(req *http.Request) (*api.Service, *http.Request, error) {
+	if r.isClosed() {
+		return nil, nil, errors.New("router closed")
 	}
-	path := strings.Split(req.URL.Path[idx:], "/")
 
+	// only routes in the domain of the request
+	t := r.table(r.domain(req))
+	path, verb := util.SplitPath(req.URL.Path)
+
+	// only check the endpoints which could match
+	return r.match(t, t.idx.Lookup(req.Method, path), req, path, verb)
+}
+
+// match returns the first of the endpoints matching the request and a copy
+// of the request with the path variables and transform rules of the endpoint
+func (r *registryRouter) match(t *table, keys []string, req *http.Request, path []string, verb string) (*api.Service, *http.Request, error) {
 	// use the first match
 	// TODO: weighted matching
-	for n, e := range r.eps {
-		cep, ok := r.ceps[n]
+	for _, n := range keys {
+		e := t.eps[n]
+		cep, ok := t.ceps[n]
 		if !ok {
 			continue
 		}
-		ep := e.Endpoint
-		var mMatch, hMatch, pMatch bool
-		// 1. try method
-		for _, m := range ep.Method {
-			if m == req.Method {
-				mMatch = true
-				break
-			}
-		}
-		if !mMatch {
+
+		stage, _, matches := check(e.Endpoint, cep, req, path, verb)
+		if len(stage) > 0 {
 			continue
 		}
-		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-			logger.Debugf("api method match %s", req.Method)
+
+		// TODO: Percentage traffic
+		// we got here, so its a match
+		return e, util.Annotate(req, matches, e.Endpoint.Body, cep.rules), nil
+	}
+
+	// no match
+	return nil, nil, errors.New("not found")
+}
+
+// check runs the match stages for an endpoint without modifying the request.
+// It returns the stage the endpoint was rejected at or the matching pattern
+// and the variables it captured.
+func check(ep *api.Endpoint, cep *endpoint, req *http.Request, path []string, verb string) (string, string, map[string]string) {
+	var mMatch, hMatch bool
+	// 1. try method
+	for _, m := range ep.Method {
+		if m == req.Method {
+			mMatch = true
+			break
 		}
+	}
+	if !mMatch {
+		return util.StageMethod, "", nil
+	}
+	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+		logger.Debugf("api method match %s", req.Method)
+	}
 
-		// 2. try host
-		if len(ep.Host) == 0 {
-			hMatch = true
-		} else {
-			for idx, h := range ep.Host {
-				if h == "" || h == "*" {
+	// 2. try host
+	if len(ep.Host) == 0 {
+		hMatch = true
+	} else {
+		for idx, h := range ep.Host {
+			if h == "" || h == "*" {
+				hMatch = true
+				break
+			} else {
+				if cep.hostregs[idx].MatchString(req.URL.Host) {
 					hMatch = true
 					break
-				} else {
-					if cep.hostregs[idx].MatchString(req.URL.Host) {
-						hMatch = true
-						break
-					}
 				}
 			}
 		}
-		if !hMatch {
+	}
+	if !hMatch {
+		return util.StageHost, "", nil
+	}
+	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+		logger.Debugf("api host match %s", req.URL.Host)
+	}
+
+	// 3. try path via google.api path matching
+	for _, pathreg := range cep.pathregs {
+		matches, err := pathreg.Match(path, verb)
+		if err != nil {
+			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				logger.Debugf("api gpath not match %s != %v", path, pathreg)
+			}
 			continue
 		}
 		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-			logger.Debugf("api host match %s", req.URL.Host)
+			logger.Debugf("api gpath match %s = %v", path, pathreg)
 		}
+		// 5. try the predicates
+		if !cep.predicates.Match(req) {
+			return util.StagePredicate, "", nil
+		}
+		return "", pathreg.String(), matches
+	}
 
-		// 3. try path via google.api path matching
-		for _, pathreg := range cep.pathregs {
-			matches, err := pathreg.Match(path, "")
-			if err != nil {
-				if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-					logger.Debugf("api gpath not match %s != %v", path, pathreg)
-				}
-				continue
-			}
+	// 4. try path via pcre path matching
+	for _, pathreg := range cep.pcreregs {
+		if !pathreg.MatchString(req.URL.Path) {
 			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-				logger.Debugf("api gpath match %s = %v", path, pathreg)
-			}
-			pMatch = true
-			ctx := req.Context()
-			md, ok := metadata.FromContext(ctx)
-			if !ok {
-				md = make(metadata.Metadata)
-			}
-			for k, v := range matches {
-				md[fmt.Sprintf("x-api-field-%s", k)] = v
+				logger.Debugf("api pcre path not match %s != %v", path, pathreg)
 			}
-			md["x-api-body"] = ep.Body
-			*req = *req.Clone(metadata.NewContext(ctx, md))
-			break
+			continue
 		}
-
-		if !pMatch {
-			// 4. try path via pcre path matching
-			for _, pathreg := range cep.pcreregs {
-				if !pathreg.MatchString(req.URL.Path) {
-					if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-						logger.Debugf("api pcre path not match %s != %v", path, pathreg)
-					}
-					continue
-				}
-				if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-					logger.Debugf("api pcre path match %s != %v", path, pathreg)
-				}
-				pMatch = true
-				break
-			}
+		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+			logger.Debugf("api pcre path match %s != %v", path, pathreg)
 		}
-
-		if !pMatch {
-			continue
+		if !cep.predicates.Match(req) {
+			return util.StagePredicate, "", nil
 		}
-
-		// TODO: Percentage traffic
-		// we got here, so its a match
-		return e, nil
+		return "", pathreg.String(), nil
 	}
 
-	// no match
-	return nil, errors.New("not found")
+	// rejected by the last stage we tried
+	if len(cep.pcreregs) > 0 {
+		return util.StagePCRE, "", nil
+	}
+	return util.StageGPath, "", nil
 }
 
-func (r *registryRouter) Route(req *http.Request) (*api.Service, error) {
+func (r *registryRouter) Route(req *http.Request) (*api.Service, *http.Request, error) {
 	if r.isClosed() {
-		return nil, errors.New("router closed")
+		return nil, nil, errors.New("router closed")
 	}
 
 	// try get an endpoint
-	ep, err := r.Endpoint(req)
+	ep, rreq, err := r.Endpoint(req)
 	if err == nil {
-		return ep, nil
+		return ep, rreq, nil
 	}
 
 	// error not nil
 	// ignore that shit
 	// TODO: don't ignore that shit
 
+	return r.Resolve(req)
+}
+
+// Resolve routes the request to the service named by the resolver
+func (r *registryRouter) Resolve(req *http.Request) (*api.Service, *http.Request, error) {
+	if r.isClosed() {
+		return nil, nil, errors.New("router closed")
+	}
+
+	service, rp, err := r.fallback(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service, req.WithContext(resolver.NewContext(req.Context(), rp)), nil
+}
+
+// fallback resolves the service for requests which match no endpoint
+func (r *registryRouter) fallback(req *http.Request) (*api.Service, *resolver.Endpoint, error) {
 	// get the service name
 	rp, err := r.opts.Resolver.Resolve(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// service name
@@ -455,7 +905,7 @@ func (r *registryRouter) Route(req *http.Request) (*api.Service, error) {
 	// get service
 	services, err := r.rc.GetService(name, registry.GetDomain(rp.Domain))
 	if err != nil {
-		return nil, err
+		return nil, rp, err
 	}
 
 	// only use endpoint matching when the meta handler is set aka api.Default
@@ -470,14 +920,23 @@ func (r *registryRouter) Route(req *http.Request) (*api.Service, error) {
 		}
 
 		// construct api service
-		return &api.Service{
+		service := &api.Service{
 			Name: name,
 			Endpoint: &api.Endpoint{
-				Name:    rp.Method,
+				Name:    rp.Endpoint,
 				Handler: handler,
 			},
 			Services: services,
-		}, nil
+		}
+
+		// a resolved endpoint is called as an rpc on the request path,
+		// otherwise the meta handler proxies to the service
+		if len(rp.Endpoint) > 0 {
+			service.Endpoint.Method = []string{req.Method}
+			service.Endpoint.Path = []string{req.URL.Path}
+		}
+
+		return service, rp, nil
 	// http handler
 	case "http", "proxy", "web":
 		// construct api service
@@ -491,10 +950,101 @@ func (r *registryRouter) Route(req *http.Request) (*api.Service, error) {
 				Path:    []string{req.URL.Path},
 			},
 			Services: services,
-		}, nil
+		}, rp, nil
 	}
 
-	return nil, errors.New("unknown handler")
+	return nil, rp, errors.New("unknown handler")
+}
+
+// Explain describes how the request would be routed
+func (r *registryRouter) Explain(req *http.Request) *router.Explanation {
+	exp := &router.Explanation{
+		Method: req.Method,
+		Host:   req.URL.Host,
+		Path:   req.URL.Path,
+	}
+
+	if r.isClosed() {
+		exp.Error = "router closed"
+		return exp
+	}
+
+	exp.Domain = r.domain(req)
+	t := r.table(exp.Domain)
+	path, verb := util.SplitPath(req.URL.Path)
+
+	// the indexed endpoints in the order they're tried then everything else
+	keys := t.idx.Lookup(req.Method, path)
+	indexed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		indexed[key] = true
+	}
+	var rest []string
+	for key := range t.eps {
+		if !indexed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	for _, key := range append(keys, rest...) {
+		e := t.eps[key]
+		cep, ok := t.ceps[key]
+		if !ok {
+			continue
+		}
+
+		c := &router.Candidate{
+			Service:  e.Name,
+			Endpoint: e.Endpoint.Name,
+			Handler:  e.Endpoint.Handler,
+			Method:   e.Endpoint.Method,
+			Host:     e.Endpoint.Host,
+			Path:     e.Endpoint.Path,
+			Domain:   exp.Domain,
+			Indexed:  indexed[key],
+		}
+		c.Rejected, c.Pattern, c.Vars = check(e.Endpoint, cep, req, path, verb)
+
+		if len(c.Rejected) == 0 {
+			if exp.Match == nil {
+				exp.Match = c
+			} else {
+				c.Rejected = router.StageShadowed
+			}
+		}
+
+		exp.Candidates = append(exp.Candidates, c)
+	}
+
+	if exp.Match != nil {
+		return exp
+	}
+
+	// no endpoint matched so the resolver decides
+	service, rp, err := r.fallback(req)
+	if rp != nil {
+		exp.Fallback = &router.Fallback{
+			Resolver: r.opts.Resolver.String(),
+			Service:  rp.Name,
+			Domain:   rp.Domain,
+		}
+		if err != nil {
+			exp.Fallback.Error = err.Error()
+		}
+	}
+	if err != nil {
+		exp.Error = err.Error()
+		return exp
+	}
+
+	exp.Fallback.Endpoint = service.Endpoint.Name
+	exp.Fallback.Handler = service.Endpoint.Handler
+	for _, s := range service.Services {
+		exp.Fallback.Nodes += len(s.Nodes)
+	}
+
+	return exp
 }
 
 func newRouter(opts ...router.Option) *registryRouter {
@@ -503,15 +1053,18 @@ func newRouter(opts ...router.Option) *registryRouter {
 		exit: make(chan bool),
 		opts: options,
 		rc:   cache.New(options.Registry),
-		eps:  make(map[string]*api.Service),
-		ceps: make(map[string]*endpoint),
+
+		services: make(map[string]map[string][]*registry.Service),
+		changed:  make(map[serviceKey]uint64),
 	}
-	go r.watch()
-	go r.refresh()
+	r.tbl.Store(tables{})
 	return r
 }
 
 // NewRouter returns the default router
 func NewRouter(opts ...router.Option) router.Router {
-	return newRouter(opts...)
+	r := newRouter(opts...)
+	go r.watch()
+	go r.refresh()
+	return r
 }
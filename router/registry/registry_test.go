@@ -17,15 +17,39 @@
 package registry
 
 import (
+	"fmt"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/resolver/rpc"
+	"github.com/micro-community/micro-webui/resolver/vpath"
+	util "github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/transform"
+	"github.com/micro/micro/v3/service/context/metadata"
 	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/registry/memory"
 	"github.com/stretchr/testify/assert"
 )
 
+// syncRouter registers the services with a fake registry and syncs a router from it
+func syncRouter(tb testing.TB, services []*registry.Service) *registryRouter {
+	reg := newFakeRegistry()
+	for _, s := range services {
+		if err := reg.Register(s); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	router := newRouter(util.WithRegistry(reg))
+	if err := router.resync(); err != nil {
+		tb.Fatal(err)
+	}
+	return router
+}
+
 func TestStoreRegex(t *testing.T) {
-	router := newRouter()
-	router.store([]*registry.Service{
+	router := syncRouter(t, []*registry.Service{
 		{
 			Name:    "Foobar",
 			Version: "latest",
@@ -46,5 +70,383 @@ func TestStoreRegex(t *testing.T) {
 	},
 	)
 
-	assert.Len(t, router.ceps["Foobar.foo"].pcreregs, 1)
+	assert.Len(t, router.table(registry.DefaultDomain).ceps["Foobar.foo"].pcreregs, 1)
+}
+
+func TestStoreTransform(t *testing.T) {
+	router := syncRouter(t, []*registry.Service{
+		{
+			Name:    "Foobar",
+			Version: "latest",
+			Endpoints: []*registry.Endpoint{
+				{
+					Name: "foo",
+					Metadata: map[string]string{
+						"endpoint":  "FooEndpoint",
+						"method":    "GET",
+						"path":      "/foo/{id}",
+						"handler":   "http",
+						"transform": `{"request":[{"type":"strip_prefix"}]}`,
+					},
+				},
+				{
+					Name: "bar",
+					Metadata: map[string]string{
+						"endpoint":  "BarEndpoint",
+						"method":    "GET",
+						"path":      "/bar/{id}",
+						"handler":   "http",
+						"transform": `{"request":[{"type":"unknown"}]}`,
+					},
+				},
+			},
+			Metadata: map[string]string{},
+		},
+	},
+	)
+
+	assert.NotNil(t, router.table(registry.DefaultDomain).ceps["Foobar.foo"].rules)
+	assert.Nil(t, router.table(registry.DefaultDomain).ceps["Foobar.bar"].rules)
+
+	req := httptest.NewRequest("GET", "/foo/1", nil)
+	_, rreq, err := router.Endpoint(req)
+	assert.Nil(t, err)
+	assert.NotNil(t, transform.FromContext(rreq.Context()))
+
+	// the request routed is left as it was
+	assert.Nil(t, transform.FromContext(req.Context()))
+	_, ok := metadata.FromContext(req.Context())
+	assert.False(t, ok)
+}
+
+func TestStorePredicates(t *testing.T) {
+	router := syncRouter(t, []*registry.Service{
+		{
+			Name:    "Foobar",
+			Version: "latest",
+			Endpoints: []*registry.Endpoint{
+				{
+					Name: "beta",
+					Metadata: map[string]string{
+						"endpoint":   "BetaEndpoint",
+						"method":     "GET",
+						"path":       "/foo/{id}",
+						"handler":    "rpc",
+						"predicates": `[{"type":"header","name":"X-Group","value":"beta"}]`,
+					},
+				},
+				{
+					Name: "broken",
+					Metadata: map[string]string{
+						"endpoint":   "BrokenEndpoint",
+						"method":     "GET",
+						"path":       "/foo/{id}",
+						"handler":    "rpc",
+						"predicates": `[{"type":"unknown"}]`,
+					},
+				},
+				{
+					Name: "stable",
+					Metadata: map[string]string{
+						"endpoint": "StableEndpoint",
+						"method":   "GET",
+						"path":     "/foo/{id}",
+						"handler":  "rpc",
+					},
+				},
+			},
+		},
+	},
+	)
+
+	// invalid predicates drop the endpoint
+	assert.Nil(t, router.table(registry.DefaultDomain).ceps["Foobar.broken"])
+
+	req := httptest.NewRequest("GET", "/foo/1", nil)
+	req.Header.Set("X-Group", "beta")
+	ep, _, err := router.Endpoint(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "BetaEndpoint", ep.Endpoint.Name)
+
+	ep, _, err = router.Endpoint(httptest.NewRequest("GET", "/foo/1", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "StableEndpoint", ep.Endpoint.Name)
+
+	exp := router.Explain(httptest.NewRequest("GET", "/foo/1", nil))
+	assert.Equal(t, util.StagePredicate, exp.Candidates[0].Rejected)
+}
+
+func TestStoreVerb(t *testing.T) {
+	router := syncRouter(t, []*registry.Service{
+		{
+			Name:    "Foobar",
+			Version: "latest",
+			Endpoints: []*registry.Endpoint{
+				{
+					Name: "Books.Publish",
+					Metadata: map[string]string{
+						"endpoint": "Books.Publish",
+						"method":   "POST",
+						"path":     "/v1/{name=books/*}:publish",
+						"handler":  "rpc",
+					},
+				},
+				{
+					Name: "Books.Get",
+					Metadata: map[string]string{
+						"endpoint": "Books.Get",
+						"method":   "POST",
+						"path":     "/v1/{name=books/*}",
+						"handler":  "rpc",
+					},
+				},
+			},
+			Metadata: map[string]string{},
+		},
+	},
+	)
+
+	svc, _, err := router.Endpoint(httptest.NewRequest("POST", "/v1/books/1:publish", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "Books.Publish", svc.Endpoint.Name)
+
+	svc, _, err = router.Endpoint(httptest.NewRequest("POST", "/v1/books/1", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "Books.Get", svc.Endpoint.Name)
+
+	_, _, err = router.Endpoint(httptest.NewRequest("POST", "/v1/books/1:unpublish", nil))
+	assert.NotNil(t, err)
+}
+
+// benchRouter registers n services each with a handful of endpoints
+func benchRouter(b *testing.B, n int) *registryRouter {
+	var services []*registry.Service
+
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("svc%d", i)
+		services = append(services, &registry.Service{
+			Name:    name,
+			Version: "latest",
+			Endpoints: []*registry.Endpoint{
+				{
+					Name: "Items.List",
+					Metadata: map[string]string{
+						"endpoint": "Items.List",
+						"method":   "GET",
+						"path":     "/" + name + "/items",
+						"handler":  "rpc",
+					},
+				},
+				{
+					Name: "Items.Read",
+					Metadata: map[string]string{
+						"endpoint": "Items.Read",
+						"method":   "GET",
+						"path":     "/" + name + "/items/{id}",
+						"handler":  "rpc",
+					},
+				},
+				{
+					Name: "Items.Update",
+					Metadata: map[string]string{
+						"endpoint": "Items.Update",
+						"method":   "PATCH",
+						"path":     "/" + name + "/items/{id}",
+						"handler":  "rpc",
+					},
+				},
+			},
+		})
+	}
+
+	return syncRouter(b, services)
+}
+
+func BenchmarkEndpointIndex(b *testing.B) {
+	router := benchRouter(b, 500)
+	req := httptest.NewRequest("GET", "/svc250/items/1", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, _, err := router.Endpoint(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEndpointScan(b *testing.B) {
+	router := benchRouter(b, 500)
+	req := httptest.NewRequest("GET", "/svc250/items/1", nil)
+
+	t := router.table(registry.DefaultDomain)
+	keys := make([]string, 0, len(t.eps))
+	for key := range t.eps {
+		keys = append(keys, key)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		path, verb := util.SplitPath(req.URL.Path)
+		if _, _, err := router.match(t, keys, req, path, verb); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	router := syncRouter(t, []*registry.Service{
+		{
+			Name:    "Foobar",
+			Version: "latest",
+			Endpoints: []*registry.Endpoint{
+				{
+					Name: "Books.Get",
+					Metadata: map[string]string{
+						"endpoint": "Books.Get",
+						"method":   "GET",
+						"path":     "/v1/books/{id}",
+						"handler":  "rpc",
+					},
+				},
+				{
+					Name: "Books.Create",
+					Metadata: map[string]string{
+						"endpoint": "Books.Create",
+						"method":   "POST",
+						"path":     "/v1/books",
+						"handler":  "rpc",
+					},
+				},
+				{
+					Name: "Books.Admin",
+					Metadata: map[string]string{
+						"endpoint": "Books.Admin",
+						"method":   "GET",
+						"host":     "admin.example.com",
+						"path":     "/v1/books/{id}",
+						"handler":  "rpc",
+					},
+				},
+				{
+					Name: "Shelves.Get",
+					Metadata: map[string]string{
+						"endpoint": "Shelves.Get",
+						"method":   "GET",
+						"path":     "/v1/shelves/{id}",
+						"handler":  "rpc",
+					},
+				},
+			},
+		},
+	},
+	)
+
+	req := httptest.NewRequest("GET", "http://example.com/v1/books/1", nil)
+	exp := router.Explain(req)
+
+	assert.Empty(t, exp.Error)
+	assert.NotNil(t, exp.Match)
+	assert.Equal(t, "Books.Get", exp.Match.Endpoint)
+	assert.Equal(t, map[string]string{"id": "1"}, exp.Match.Vars)
+	assert.Nil(t, exp.Fallback)
+
+	stages := map[string]string{}
+	for _, c := range exp.Candidates {
+		stages[c.Endpoint] = c.Rejected
+	}
+	assert.Equal(t, map[string]string{
+		"Books.Get":    "",
+		"Books.Admin":  util.StageHost,
+		"Books.Create": util.StageMethod,
+		"Shelves.Get":  util.StageGPath,
+	}, stages)
+
+	// explaining has no side effects on the request
+	assert.Nil(t, transform.FromContext(req.Context()))
+	_, ok := metadata.FromContext(req.Context())
+	assert.False(t, ok)
+}
+
+func TestRoutes(t *testing.T) {
+	endpoints := []*registry.Endpoint{
+		{
+			Name: "Books.Get",
+			Metadata: map[string]string{
+				"endpoint": "Books.Get",
+				"method":   "GET",
+				"path":     "/v1/{name=books/*}",
+				"handler":  "rpc",
+			},
+		},
+		{
+			Name: "Books.Search",
+			Metadata: map[string]string{
+				"endpoint": "Books.Search",
+				"method":   "GET",
+				"path":     "^/v1/search/.*$",
+				"handler":  "rpc",
+			},
+		},
+	}
+
+	router := syncRouter(t, []*registry.Service{
+		{Name: "Foobar", Version: "v1", Endpoints: endpoints},
+		{Name: "Foobar", Version: "v2", Endpoints: endpoints},
+	})
+
+	routes := router.Routes()
+	assert.Len(t, routes, 2)
+
+	assert.Equal(t, "Foobar", routes[0].Service)
+	assert.Equal(t, "Books.Get", routes[0].Endpoint.Name)
+	assert.Equal(t, []string{"v1", "v2"}, routes[0].Versions)
+	assert.Equal(t, []string{"/v1/{name=books/*}"}, routes[0].Templates)
+
+	assert.Equal(t, "Books.Search", routes[1].Endpoint.Name)
+	assert.Equal(t, []string{"^/v1/search/.*$"}, routes[1].Templates)
+}
+
+func TestFallback(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(&registry.Service{
+		Name:    "micro.greeter",
+		Version: "latest",
+		Nodes:   []*registry.Node{{Id: "greeter-1", Address: "127.0.0.1:8080"}},
+	})
+
+	// the rpc resolver knows the endpoint
+	router := newRouter(
+		util.WithRegistry(reg),
+		util.WithResolver(rpc.NewResolver(resolver.WithServicePrefix("micro"))),
+	)
+	defer router.Close()
+
+	svc, _, err := router.Route(httptest.NewRequest("POST", "/greeter/say/hello", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "micro.greeter", svc.Name)
+	assert.Equal(t, "Say.Hello", svc.Endpoint.Name)
+	assert.Equal(t, "rpc", svc.Endpoint.Handler)
+	assert.Equal(t, []string{"/greeter/say/hello"}, svc.Endpoint.Path)
+
+	// the path resolvers only know the service so the request is proxied
+	router = newRouter(
+		util.WithRegistry(reg),
+		util.WithResolver(vpath.NewResolver(resolver.WithServicePrefix("micro"))),
+	)
+	defer router.Close()
+
+	svc, req, err := router.Route(httptest.NewRequest("POST", "/greeter/say/hello", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "micro.greeter", svc.Name)
+	assert.Empty(t, svc.Endpoint.Name)
+	assert.Empty(t, svc.Endpoint.Path)
+
+	// the path the resolver named the service with
+	rp, ok := resolver.FromContext(req.Context())
+	assert.True(t, ok)
+	assert.Equal(t, "/greeter", rp.Prefix)
 }
@@ -0,0 +1,179 @@
+package registry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+var (
+	// DefaultResyncInterval is how often the routes are fully resynced
+	DefaultResyncInterval = time.Minute * 10
+
+	// bounds of the backoff between failed watches and resyncs
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// backoff returns an exponential delay with jitter for the attempt
+func backoff(attempts int) time.Duration {
+	if attempts > 16 {
+		attempts = 16
+	}
+	d := minBackoff << uint(attempts)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	// half fixed, half random so retries spread out but never spin
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// stats tracks the freshness of the routes
+type stats struct {
+	sync.Mutex
+	router.Stats
+	// when the watcher disconnected, zero when connected
+	disconnected time.Time
+}
+
+func (s *stats) connect() {
+	s.Lock()
+	s.Connected = true
+	s.disconnected = time.Time{}
+	s.Unlock()
+}
+
+func (s *stats) disconnect() {
+	s.Lock()
+	if s.Connected {
+		s.Reconnects++
+		s.disconnected = time.Now()
+	}
+	s.Connected = false
+	s.Unlock()
+}
+
+func (s *stats) event() {
+	s.Lock()
+	s.Events++
+	s.LastEvent = time.Now()
+	s.Unlock()
+}
+
+func (s *stats) resynced() {
+	s.Lock()
+	s.Resyncs++
+	s.LastSync = time.Now()
+	s.Unlock()
+}
+
+func (s *stats) failed() {
+	s.Lock()
+	s.Errors++
+	s.Unlock()
+}
+
+// snapshot copies the stats working out the staleness
+func (s *stats) snapshot() *router.Stats {
+	s.Lock()
+	defer s.Unlock()
+
+	st := s.Stats
+
+	switch {
+	case !s.disconnected.IsZero():
+		st.Staleness = time.Since(s.disconnected)
+	case !s.Connected && !s.LastSync.IsZero():
+		st.Staleness = time.Since(s.LastSync)
+	}
+
+	return &st
+}
+
+// addVersion merges a created or updated service version into the versions
+func addVersion(versions []*registry.Service, s *registry.Service) []*registry.Service {
+	updated := make([]*registry.Service, 0, len(versions)+1)
+
+	var found bool
+
+	for _, cur := range versions {
+		if cur.Version != s.Version {
+			updated = append(updated, cur)
+			continue
+		}
+
+		found = true
+
+		svc := *s
+		// keep what the event doesn't tell us
+		if len(svc.Endpoints) == 0 {
+			svc.Endpoints = cur.Endpoints
+		}
+		if len(svc.Metadata) == 0 {
+			svc.Metadata = cur.Metadata
+		}
+
+		// append old nodes to new service
+		svc.Nodes = append([]*registry.Node{}, s.Nodes...)
+		for _, node := range cur.Nodes {
+			if !hasNode(s.Nodes, node.Id) {
+				svc.Nodes = append(svc.Nodes, node)
+			}
+		}
+
+		updated = append(updated, &svc)
+	}
+
+	if !found {
+		svc := *s
+		updated = append(updated, &svc)
+	}
+
+	return updated
+}
+
+// delVersion removes the nodes of a deleted service version, dropping the
+// version when it has no nodes left or the event has none
+func delVersion(versions []*registry.Service, s *registry.Service) []*registry.Service {
+	updated := make([]*registry.Service, 0, len(versions))
+
+	for _, cur := range versions {
+		if cur.Version != s.Version {
+			updated = append(updated, cur)
+			continue
+		}
+
+		if len(s.Nodes) == 0 {
+			continue
+		}
+
+		var nodes []*registry.Node
+		for _, node := range cur.Nodes {
+			if !hasNode(s.Nodes, node.Id) {
+				nodes = append(nodes, node)
+			}
+		}
+
+		if len(nodes) == 0 {
+			continue
+		}
+
+		svc := *cur
+		svc.Nodes = nodes
+		updated = append(updated, &svc)
+	}
+
+	return updated
+}
+
+func hasNode(nodes []*registry.Node, id string) bool {
+	for _, n := range nodes {
+		if n.Id == id {
+			return true
+		}
+	}
+	return false
+}
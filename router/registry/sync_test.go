@@ -0,0 +1,345 @@
+package registry
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/micro-community/micro-webui/resolver/subdomain"
+	"github.com/micro-community/micro-webui/resolver/vpath"
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/registry/memory"
+)
+
+// fakeWatcher delivers the events and errors injected by a test
+type fakeWatcher struct {
+	events chan *registry.Result
+	errs   chan error
+	exit   chan bool
+}
+
+func (w *fakeWatcher) Next() (*registry.Result, error) {
+	select {
+	case res := <-w.events:
+		return res, nil
+	case err := <-w.errs:
+		return nil, err
+	case <-w.exit:
+		return nil, errors.New("watcher stopped")
+	}
+}
+
+func (w *fakeWatcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+}
+
+// fakeRegistry is a memory registry whose watchers are driven by the test
+type fakeRegistry struct {
+	registry.Registry
+	events   chan *registry.Result
+	errs     chan error
+	watchers chan bool
+	// when set, lookups of the service wait for the test to release them
+	// once they've read the registry
+	hold string
+	held chan chan bool
+}
+
+func (r *fakeRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	services, err := r.Registry.GetService(name, opts...)
+	if len(r.hold) > 0 && name == r.hold {
+		release := make(chan bool)
+		r.held <- release
+		<-release
+	}
+	return services, err
+}
+
+func (r *fakeRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	r.watchers <- true
+	return &fakeWatcher{events: r.events, errs: r.errs, exit: make(chan bool)}, nil
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		Registry: memory.NewRegistry(),
+		events:   make(chan *registry.Result),
+		errs:     make(chan error),
+		watchers: make(chan bool, 10),
+	}
+}
+
+func testService(name, path string) *registry.Service {
+	return &registry.Service{
+		Name:    name,
+		Version: "latest",
+		Nodes:   []*registry.Node{{Id: name + "-1", Address: "127.0.0.1:8080"}},
+		Endpoints: []*registry.Endpoint{
+			{
+				Name: "Handler.Call",
+				Metadata: map[string]string{
+					"endpoint": "Handler.Call",
+					"method":   "GET",
+					"path":     path,
+					"handler":  "rpc",
+				},
+			},
+		},
+	}
+}
+
+// eventually waits for the router to match, or not, the path
+func eventually(t *testing.T, r *registryRouter, path string, match bool) {
+	for i := 0; i < 100; i++ {
+		_, _, err := r.Endpoint(httptest.NewRequest("GET", path, nil))
+		if (err == nil) == match {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("Expected %s match to be %v", path, match)
+}
+
+func TestWatchEvents(t *testing.T) {
+	minBackoff, maxBackoff = time.Millisecond, time.Millisecond*10
+
+	reg := newFakeRegistry()
+	reg.Register(testService("foo", "/foo"))
+
+	r := NewRouter(router.WithRegistry(reg), router.WithResyncInterval(-1)).(*registryRouter)
+	defer r.Close()
+
+	<-reg.watchers
+
+	// the initial sync
+	eventually(t, r, "/foo", true)
+
+	// events are applied directly without looking up the registry
+	reg.events <- &registry.Result{Action: "create", Service: testService("bar", "/bar")}
+	eventually(t, r, "/bar", true)
+
+	// a second node joins
+	node := testService("bar", "/bar")
+	node.Nodes[0].Id = "bar-2"
+	reg.events <- &registry.Result{Action: "update", Service: node}
+
+	// the first node leaves
+	reg.events <- &registry.Result{Action: "delete", Service: testService("bar", "/bar")}
+
+	var nodes []*registry.Node
+	for i := 0; i < 100; i++ {
+		svc, _, err := r.Endpoint(httptest.NewRequest("GET", "/bar", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodes = svc.Services[0].Nodes; len(nodes) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if len(nodes) != 1 || nodes[0].Id != "bar-2" {
+		t.Fatalf("Expected node bar-2 got %+v", nodes)
+	}
+
+	// the last node leaves
+	reg.events <- &registry.Result{Action: "delete", Service: node}
+	eventually(t, r, "/bar", false)
+
+	// changes while disconnected are picked up by the resync on reconnect
+	reg.Register(testService("baz", "/baz"))
+	reg.errs <- errors.New("connection reset")
+
+	select {
+	case <-reg.watchers:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the watcher to reconnect")
+	}
+
+	eventually(t, r, "/baz", true)
+
+	st := r.Stats()
+	if st.Reconnects != 1 {
+		t.Fatalf("Expected 1 reconnect got %d", st.Reconnects)
+	}
+	if st.Events != 4 {
+		t.Fatalf("Expected 4 events got %d", st.Events)
+	}
+	if st.Services != 2 {
+		t.Fatalf("Expected 2 services got %d", st.Services)
+	}
+}
+
+func TestResyncWithEvents(t *testing.T) {
+	reg := newFakeRegistry()
+	reg.Register(testService("foo", "/foo"))
+	reg.Register(testService("baz", "/baz"))
+	reg.hold, reg.held = "foo", make(chan chan bool)
+
+	r := newRouter(router.WithRegistry(reg))
+	defer r.Close()
+
+	errs := make(chan error)
+	go func() {
+		errs <- r.resync()
+	}()
+
+	// events arrive while the resync is listing
+	release := <-reg.held
+	r.process(&registry.Result{Action: "create", Service: testService("bar", "/bar")}, registry.DefaultDomain)
+	r.process(&registry.Result{Action: "delete", Service: testService("foo", "/foo")}, registry.DefaultDomain)
+	close(release)
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		path  string
+		match bool
+	}{
+		// created by the event, not in the listing
+		{"/bar", true},
+		// deleted by the event, still in the listing
+		{"/foo", false},
+		// only in the listing
+		{"/baz", true},
+	}
+
+	for _, d := range testData {
+		if _, _, err := r.Endpoint(httptest.NewRequest("GET", d.path, nil)); (err == nil) != d.match {
+			t.Fatalf("Expected %s match to be %v got %v", d.path, d.match, err)
+		}
+	}
+
+	// a later resync takes the registry as it is
+	reg.hold = ""
+	if err := r.resync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Endpoint(httptest.NewRequest("GET", "/foo", nil)); err != nil {
+		t.Fatalf("Expected /foo to match after resync got %v", err)
+	}
+}
+
+func TestOverlappingResyncs(t *testing.T) {
+	reg := newFakeRegistry()
+	reg.Register(testService("foo", "/foo"))
+	reg.hold, reg.held = "foo", make(chan chan bool)
+
+	r := newRouter(router.WithRegistry(reg))
+	defer r.Close()
+
+	errs := make(chan error, 2)
+	resync := func() {
+		errs <- r.resync()
+	}
+
+	// the first resync reads foo
+	go resync()
+	first := <-reg.held
+
+	// foo leaves before a second resync starts
+	reg.Deregister(testService("foo", "/foo"))
+	r.process(&registry.Result{Action: "delete", Service: testService("foo", "/foo")}, registry.DefaultDomain)
+	go resync()
+
+	// the second resync would finish first if they overlapped
+	pending := 2
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending--
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(first)
+	for ; pending > 0; pending-- {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := r.Endpoint(httptest.NewRequest("GET", "/foo", nil)); err == nil {
+		t.Fatal("Expected the older listing not to bring back foo")
+	}
+}
+
+func TestDomains(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(testService("foo", "/foo"))
+	reg.Register(testService("bar", "/bar"), registry.RegisterDomain("tenant"))
+
+	r := newRouter(
+		router.WithRegistry(reg),
+		router.WithDomains(registry.WildcardDomain),
+		router.WithResolver(subdomain.NewResolver(vpath.NewResolver())),
+	)
+	defer r.Close()
+
+	if err := r.resync(); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		host  string
+		path  string
+		match bool
+	}{
+		{"localhost", "/foo", true},
+		{"localhost", "/bar", false},
+		{"tenant.example.com", "/bar", true},
+		{"tenant.example.com", "/foo", false},
+		{"other.example.com", "/bar", false},
+	}
+
+	for _, d := range testData {
+		req := httptest.NewRequest("GET", "http://"+d.host+d.path, nil)
+		if _, _, err := r.Endpoint(req); (err == nil) != d.match {
+			t.Fatalf("Expected %s%s match to be %v got %v", d.host, d.path, d.match, err)
+		}
+	}
+
+	// events land in the domain of the service
+	svc := testService("baz", "/baz")
+	svc.Metadata = map[string]string{"domain": "other"}
+	r.process(&registry.Result{Action: "create", Service: svc}, registry.WildcardDomain)
+
+	req := httptest.NewRequest("GET", "http://other.example.com/baz", nil)
+	if _, _, err := r.Endpoint(req); err != nil {
+		t.Fatal(err)
+	}
+
+	st := r.Stats()
+	if st.Domains != 3 || st.Services != 3 {
+		t.Fatalf("Expected 3 services in 3 domains got %d in %d", st.Services, st.Domains)
+	}
+
+	for _, route := range r.Routes() {
+		if route.Service == "bar" && route.Domain != "tenant" {
+			t.Fatalf("Expected bar in the tenant domain got %q", route.Domain)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	minBackoff, maxBackoff = time.Second, time.Minute
+
+	for attempts := 1; attempts < 100; attempts++ {
+		d := backoff(attempts)
+		if d < minBackoff || d > maxBackoff {
+			t.Fatalf("Backoff %v for attempt %d out of bounds", d, attempts)
+		}
+	}
+
+	if d := backoff(1); d > 2*time.Second {
+		t.Fatalf("Expected first backoff under 2s got %v", d)
+	}
+}
@@ -29,12 +29,39 @@ type Router interface {
 	Options() Options
 	// Stop the router
 	Close() error
-	// Endpoint returns an api.Service endpoint or an error if it does not exist
-	Endpoint(r *http.Request) (*api.Service, error)
+	// Endpoint returns an api.Service endpoint or an error if it does not exist,
+	// with a copy of the request carrying what the endpoint matched
+	Endpoint(r *http.Request) (*api.Service, *http.Request, error)
 	// Register endpoint in router
-	Register(ep *api.Endpoint) error
+	Register(ep *api.Endpoint, opts ...RegisterOption) error
 	// Deregister endpoint from router
 	Deregister(ep *api.Endpoint) error
-	// Route returns an api.Service route
-	Route(r *http.Request) (*api.Service, error)
+	// Route returns an api.Service route, with a copy of the request
+	// carrying what the route matched
+	Route(r *http.Request) (*api.Service, *http.Request, error)
+	// Routes returns the registered endpoints
+	Routes() []*Route
+}
+
+// Resolver is implemented by routers which route requests matching none of
+// their endpoints by resolving the service
+type Resolver interface {
+	// Resolve routes the request by its service without matching endpoints
+	Resolve(r *http.Request) (*api.Service, *http.Request, error)
+}
+
+// Route is an endpoint registered with a router
+type Route struct {
+	// Service the endpoint routes to
+	Service string `json:"service"`
+	// Domain is the registry domain of the service
+	Domain string `json:"domain,omitempty"`
+	// Versions of the service which registered the endpoint
+	Versions []string `json:"versions,omitempty"`
+	// Endpoint as registered
+	Endpoint *api.Endpoint `json:"endpoint"`
+	// Templates are the compiled path templates and regexps
+	Templates []string `json:"templates"`
+	// Layer is the router the endpoint is registered with in a chain
+	Layer string `json:"layer,omitempty"`
 }
@@ -274,6 +274,21 @@ func (p Pattern) String() string {
 	return "/" + segs
 }
 
+// SplitPath splits a request path into the components and verb passed to Match,
+// e.g. /v1/books/1:publish is [v1 books 1] and publish
+func SplitPath(path string) ([]string, string) {
+	components := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	var verb string
+	l := len(components)
+	if i := strings.LastIndex(components[l-1], ":"); i > 0 {
+		c := components[l-1]
+		components[l-1], verb = c[:i], c[i+1:]
+	}
+
+	return components, verb
+}
+
 // AssumeColonVerbOpt indicates whether a path suffix after a final
 // colon may only be interpreted as a verb.
 func AssumeColonVerbOpt(val bool) PatternOpt {
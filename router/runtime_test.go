@@ -0,0 +1,103 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPath(t *testing.T) {
+	for _, spec := range []struct {
+		path       string
+		components []string
+		verb       string
+	}{
+		{"/", []string{""}, ""},
+		{"/v1/books", []string{"v1", "books"}, ""},
+		{"/v1/books/1:publish", []string{"v1", "books", "1"}, "publish"},
+		{"/v1/books:batchGet", []string{"v1", "books"}, "batchGet"},
+		{"/v1/a:b/c", []string{"v1", "a:b", "c"}, ""},
+		{"/v1/books/:publish", []string{"v1", "books", ":publish"}, ""},
+	} {
+		components, verb := SplitPath(spec.path)
+		if !reflect.DeepEqual(components, spec.components) {
+			t.Errorf("SplitPath(%q) components = %q; want %q", spec.path, components, spec.components)
+		}
+		if verb != spec.verb {
+			t.Errorf("SplitPath(%q) verb = %q; want %q", spec.path, verb, spec.verb)
+		}
+	}
+}
+
+func TestPatternVerb(t *testing.T) {
+	for _, spec := range []struct {
+		tmpl            string
+		assumeColonVerb bool
+		path            string
+
+		match bool
+		vars  map[string]string
+	}{
+		{
+			tmpl:            "/v1/{name=books/*}:publish",
+			assumeColonVerb: true,
+			path:            "/v1/books/1:publish",
+			match:           true,
+			vars:            map[string]string{"name": "books/1"},
+		},
+		{
+			tmpl:            "/v1/{name=books/*}:publish",
+			assumeColonVerb: true,
+			path:            "/v1/books/1",
+		},
+		{
+			tmpl:            "/v1/{name=books/*}:publish",
+			assumeColonVerb: true,
+			path:            "/v1/books/1:unpublish",
+		},
+		{
+			tmpl:            "/v1/{name=books/*}",
+			assumeColonVerb: true,
+			path:            "/v1/books/1:publish",
+		},
+		{
+			tmpl:            "/v1/{name=books/*}",
+			assumeColonVerb: false,
+			path:            "/v1/books/1:publish",
+			match:           true,
+			vars:            map[string]string{"name": "books/1:publish"},
+		},
+		{
+			tmpl:            "/v1/{name=books/*}:publish",
+			assumeColonVerb: false,
+			path:            "/v1/books/1:publish",
+			match:           true,
+			vars:            map[string]string{"name": "books/1"},
+		},
+	} {
+		rule, err := Parse(spec.tmpl)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed with %v", spec.tmpl, err)
+		}
+		tpl := rule.Compile()
+		pat, err := NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, tpl.Verb, AssumeColonVerbOpt(spec.assumeColonVerb))
+		if err != nil {
+			t.Fatalf("NewPattern(%q) failed with %v", spec.tmpl, err)
+		}
+
+		components, verb := SplitPath(spec.path)
+		vars, err := pat.Match(components, verb)
+		if !spec.match {
+			if err == nil {
+				t.Errorf("%q.Match(%q) succeeded; want failure (assumeColonVerb=%v)", spec.tmpl, spec.path, spec.assumeColonVerb)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q.Match(%q) failed with %v (assumeColonVerb=%v)", spec.tmpl, spec.path, err, spec.assumeColonVerb)
+			continue
+		}
+		if !reflect.DeepEqual(vars, spec.vars) {
+			t.Errorf("%q.Match(%q) = %q; want %q", spec.tmpl, spec.path, vars, spec.vars)
+		}
+	}
+}
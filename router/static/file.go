@@ -0,0 +1,249 @@
+package static
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/micro-community/micro-webui/router/predicate"
+	"github.com/micro-community/micro-webui/router/transform"
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/logger"
+	"gopkg.in/yaml.v2"
+)
+
+// reloadDelay batches the events of a single file save
+var reloadDelay = time.Millisecond * 100
+
+// Route is a route declared in a routes file
+type Route struct {
+	// Endpoint is the target service.endpoint e.g greeter.Say.Hello
+	Endpoint string   `json:"endpoint" yaml:"endpoint"`
+	Handler  string   `json:"handler" yaml:"handler"`
+	Host     []string `json:"host" yaml:"host"`
+	Method   []string `json:"method" yaml:"method"`
+	// Path is a list of google.api path templates or pcre regexps
+	Path   []string `json:"path" yaml:"path"`
+	Body   string   `json:"body" yaml:"body"`
+	Stream bool     `json:"stream" yaml:"stream"`
+	// Transform is an optional set of rules applied to the request and response
+	Transform *transform.Rules `json:"transform" yaml:"transform"`
+	// Predicates the request must also satisfy e.g a header or cookie
+	Predicates predicate.Predicates `json:"predicates" yaml:"predicates"`
+}
+
+// File is the format of a routes file
+type File struct {
+	Routes []*Route `json:"routes" yaml:"routes"`
+}
+
+// ParseFile decodes a yaml or json routes file, json is used for a .json extension.
+// Unknown fields are an error in either so a misspelt restriction isn't dropped.
+func ParseFile(name string, b []byte) (*File, error) {
+	f := new(File)
+
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.DisallowUnknownFields()
+		err = d.Decode(f)
+	} else {
+		err = yaml.UnmarshalStrict(b, f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid routes file %s: %v", name, err)
+	}
+
+	return f, nil
+}
+
+// compiled is a validated route from the file
+type compiled struct {
+	ep    *api.Endpoint
+	rules *transform.Rules
+	preds predicate.Predicates
+}
+
+// compile validates the routes returning every error found
+func (f *File) compile() ([]*compiled, error) {
+	var routes []*compiled
+	var errs []string
+
+	seen := make(map[string]bool)
+
+	for i, route := range f.Routes {
+		c, err := route.compile()
+		if err == nil && seen[route.Endpoint] {
+			err = fmt.Errorf("duplicate endpoint %s", route.Endpoint)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("route %d: %v", i, err))
+			continue
+		}
+		seen[route.Endpoint] = true
+		routes = append(routes, c)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid routes: %s", strings.Join(errs, "; "))
+	}
+
+	return routes, nil
+}
+
+func (r *Route) compile() (*compiled, error) {
+	if parts := strings.Split(r.Endpoint, "."); len(parts) < 2 || len(parts[0]) == 0 {
+		return nil, fmt.Errorf("endpoint %q must be service.endpoint", r.Endpoint)
+	}
+	if len(r.Method) == 0 {
+		return nil, fmt.Errorf("method required")
+	}
+	if len(r.Path) == 0 {
+		return nil, fmt.Errorf("path required")
+	}
+	for _, p := range r.Path {
+		if len(p) == 0 {
+			return nil, fmt.Errorf("empty path")
+		}
+	}
+
+	handler := r.Handler
+	if len(handler) == 0 {
+		handler = "rpc"
+	}
+
+	c := &compiled{
+		ep: &api.Endpoint{
+			Name:    r.Endpoint,
+			Handler: handler,
+			Host:    r.Host,
+			Method:  r.Method,
+			Path:    r.Path,
+			Body:    r.Body,
+			Stream:  r.Stream,
+		},
+	}
+
+	if r.Transform != nil {
+		if err := r.Transform.Compile(); err != nil {
+			return nil, err
+		}
+		c.rules = r.Transform
+	}
+
+	if err := r.Predicates.Compile(); err != nil {
+		return nil, err
+	}
+	c.preds = r.Predicates
+
+	return c, nil
+}
+
+// Load replaces the routes loaded from a file with the routes in the file.
+// The current routes are kept if any route in the file is invalid.
+func (r *staticRouter) Load(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := ParseFile(path, b)
+	if err != nil {
+		return err
+	}
+
+	routes, err := f.compile()
+	if err != nil {
+		return err
+	}
+
+	eps := make(map[string]*endpoint, len(routes))
+	for _, route := range routes {
+		ep, err := r.compile(route.ep, route.rules, route.preds)
+		if err != nil {
+			return fmt.Errorf("invalid route %s: %v", route.ep.Name, err)
+		}
+		eps[ep.apiep.Name] = ep
+	}
+
+	r.update(func(all map[string]*endpoint) {
+		// drop what we loaded last time
+		for name := range r.loaded {
+			delete(all, name)
+		}
+		r.loaded = make(map[string]bool, len(eps))
+		for name, ep := range eps {
+			all[name] = ep
+			r.loaded[name] = true
+		}
+	})
+
+	return nil
+}
+
+// watch reloads the routes file when it changes
+func (r *staticRouter) watch() {
+	path := r.opts.RoutesFile
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("unable to watch routes file %s: %v", path, err)
+		}
+		return
+	}
+	defer w.Close()
+
+	// watch the directory as editors and config maps replace the file
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("unable to watch routes file %s: %v", path, err)
+		}
+		return
+	}
+
+	name := filepath.Clean(path)
+	var reload <-chan time.Time
+
+	for {
+		select {
+		case <-r.exit:
+			return
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			// config maps swap a ..data symlink in the directory
+			if filepath.Clean(ev.Name) != name && !strings.Contains(ev.Name, "..data") {
+				continue
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			reload = time.After(reloadDelay)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error watching routes file %s: %v", path, err)
+			}
+		case <-reload:
+			reload = nil
+			if err := r.Load(path); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("keeping previous routes, unable to reload %s: %v", path, err)
+				}
+				continue
+			}
+			if logger.V(logger.InfoLevel, logger.DefaultLogger) {
+				logger.Infof("reloaded routes from %s", path)
+			}
+		}
+	}
+}
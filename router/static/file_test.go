@@ -0,0 +1,213 @@
+package static
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/predicate"
+	"github.com/micro/micro/v3/service/api"
+)
+
+const routesYAML = `
+routes:
+  - endpoint: greeter.Say.Hello
+    method: [POST]
+    path: ["/v1/greeter/{name}"]
+    body: "*"
+    transform:
+      request:
+        - type: set_header
+          name: X-Greeter
+          value: v1
+  - endpoint: greeter.Say.Stream
+    handler: rpc
+    method: [GET]
+    host: [example.com]
+    path: ["^/v1/stream/.*$"]
+    stream: true
+    predicates:
+      - type: cookie
+        name: group
+        value: beta
+`
+
+const routesJSON = `{
+  "routes": [
+    {"endpoint": "greeter.Say.Hello", "method": ["POST"], "path": ["/v2/greeter/{name}"],
+     "transform": {"request": [{"type": "strip_prefix", "value": "/v2"}]}}
+  ]
+}`
+
+func writeFile(t *testing.T, path, data string) {
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEndpoint(name, path string) *api.Endpoint {
+	return &api.Endpoint{
+		Name:    name,
+		Handler: "rpc",
+		Method:  []string{"GET"},
+		Path:    []string{path},
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	f, err := ParseFile("routes.yaml", []byte(routesYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Routes) != 2 {
+		t.Fatalf("Expected 2 routes got %d", len(f.Routes))
+	}
+	if r := f.Routes[0]; r.Transform == nil || len(r.Transform.Request) != 1 || r.Transform.Request[0].Name != "X-Greeter" {
+		t.Fatalf("Unexpected transform %+v", r.Transform)
+	}
+	if r := f.Routes[1]; !r.Stream || r.Host[0] != "example.com" || len(r.Predicates) != 1 {
+		t.Fatalf("Unexpected route %+v", r)
+	}
+
+	f, err = ParseFile("routes.json", []byte(routesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Routes) != 1 || f.Routes[0].Path[0] != "/v2/greeter/{name}" {
+		t.Fatalf("Unexpected routes %+v", f.Routes)
+	}
+	if r := f.Routes[0]; r.Transform == nil || len(r.Transform.Request) != 1 || r.Transform.Request[0].Value != "/v2" {
+		t.Fatalf("Unexpected transform %+v", r.Transform)
+	}
+
+	if _, err := ParseFile("routes.yaml", []byte("routes:\n  - endpont: foo.Bar\n")); err == nil {
+		t.Fatal("Expected error for unknown field")
+	}
+	if _, err := ParseFile("routes.json", []byte(`{"routes": [{"endpoint": "foo.Bar", "predicate": [{"type": "cookie", "name": "group"}]}]}`)); err == nil {
+		t.Fatal("Expected error for unknown json field")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	writeFile(t, path, routesYAML)
+
+	r := NewRouter()
+	defer r.Close()
+
+	// routes registered in code survive reloads
+	if err := r.Register(testEndpoint("other.Foo.Bar", "/foo")); err != nil {
+		t.Fatal(err)
+	}
+	beta := predicate.Predicates{{Type: predicate.Header, Name: "X-Group", Value: "beta"}}
+	if err := r.Register(testEndpoint("other.Foo.Beta", "/beta"), router.RegisterPredicates(beta)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if l := len(r.Routes()); l != 4 {
+		t.Fatalf("Expected 4 routes got %d", l)
+	}
+
+	ep, _, err := r.endpoint(httptest.NewRequest("POST", "/v1/greeter/john", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.apiep.Name != "greeter.Say.Hello" || ep.apiep.Handler != "rpc" {
+		t.Fatalf("Unexpected endpoint %+v", ep.apiep)
+	}
+	if ep.rules == nil || len(ep.rules.Request) != 1 {
+		t.Fatalf("Expected the transform rules to be loaded got %+v", ep.rules)
+	}
+
+	// the stream route needs the beta cookie
+	req := httptest.NewRequest("GET", "http://example.com/v1/stream/foo", nil)
+	if _, _, err := r.endpoint(req); err == nil {
+		t.Fatal("Expected stream route to need the cookie")
+	}
+	req.AddCookie(&http.Cookie{Name: "group", Value: "beta"})
+	if _, _, err := r.endpoint(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// as does the route registered with predicates
+	req = httptest.NewRequest("GET", "/beta", nil)
+	if _, _, err := r.endpoint(req); err == nil {
+		t.Fatal("Expected beta route to need the header")
+	}
+	req.Header.Set("X-Group", "beta")
+	if _, _, err := r.endpoint(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// an invalid file keeps the last good routes
+	writeFile(t, path, `
+routes:
+  - endpoint: greeter.Say.Hello
+    method: [POST]
+    path: ["/v3/{name"]
+  - endpoint: nodot
+    method: [GET]
+    path: ["/x"]
+  - endpoint: greeter.Say.Bad
+    method: [GET]
+    path: ["/y"]
+    transform:
+      request:
+        - type: set_header
+`)
+	if err := r.Load(path); err == nil {
+		t.Fatal("Expected error loading invalid routes")
+	}
+	if _, _, err := r.endpoint(httptest.NewRequest("POST", "/v1/greeter/john", nil)); err != nil {
+		t.Fatalf("Expected previous routes to be kept: %v", err)
+	}
+
+	// a valid file replaces the loaded routes
+	writeFile(t, path, routesJSON)
+	if err := r.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if l := len(r.Routes()); l != 3 {
+		t.Fatalf("Expected 3 routes got %d", l)
+	}
+	if _, _, err := r.endpoint(httptest.NewRequest("POST", "/v1/greeter/john", nil)); err == nil {
+		t.Fatal("Expected old route to be removed")
+	}
+	if _, _, err := r.endpoint(httptest.NewRequest("GET", "/foo", nil)); err != nil {
+		t.Fatalf("Expected registered route to be kept: %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	reloadDelay = time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	writeFile(t, path, routesYAML)
+
+	r := NewRouter(router.WithRoutesFile(path))
+	defer r.Close()
+
+	if l := len(r.Routes()); l != 2 {
+		t.Fatalf("Expected 2 routes got %d", l)
+	}
+
+	// give the watcher a moment to start
+	time.Sleep(time.Millisecond * 50)
+	writeFile(t, path, routesJSON)
+
+	for i := 0; i < 100; i++ {
+		if _, _, err := r.endpoint(httptest.NewRequest("POST", "/v2/greeter/john", nil)); err == nil {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatal("Routes file was not reloaded")
+}
@@ -21,14 +21,17 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	rutil "github.com/micro-community/micro-webui/helper/registry"
 	"github.com/micro-community/micro-webui/router"
 	util "github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/predicate"
+	"github.com/micro-community/micro-webui/router/transform"
 	"github.com/micro/micro/v3/service/api"
-	"github.com/micro/micro/v3/service/context/metadata"
 	"github.com/micro/micro/v3/service/logger"
 	"github.com/micro/micro/v3/service/registry"
 )
@@ -38,14 +41,68 @@ type endpoint struct {
 	hostregs []*regexp.Regexp
 	pathregs []util.Pattern
 	pcreregs []*regexp.Regexp
+	rules    *transform.Rules
+	// header, query, cookie and client predicates
+	predicates predicate.Predicates
+}
+
+// table is an immutable snapshot of the routes, replaced on every change
+type table struct {
+	eps map[string]*endpoint
+	// index of endpoint names by method and path prefix
+	idx *util.Index
 }
 
 // router is the default router
 type staticRouter struct {
 	exit chan bool
 	opts router.Options
-	sync.RWMutex
-	eps map[string]*endpoint
+	// serialises updates to the table
+	sync.Mutex
+	// the current *table, read without locking
+	tbl atomic.Value
+	// names of the endpoints loaded from the routes file
+	loaded map[string]bool
+}
+
+// table returns the current routes
+func (r *staticRouter) table() *table {
+	return r.tbl.Load().(*table)
+}
+
+// update copies the routes, applies the change and swaps in the result
+func (r *staticRouter) update(fn func(eps map[string]*endpoint)) {
+	r.Lock()
+	defer r.Unlock()
+
+	old := r.table()
+	eps := make(map[string]*endpoint, len(old.eps)+1)
+	for name, ep := range old.eps {
+		eps[name] = ep
+	}
+
+	fn(eps)
+
+	r.tbl.Store(&table{eps: eps, idx: index(eps)})
+}
+
+// index builds the route index of the endpoints
+func index(eps map[string]*endpoint) *util.Index {
+	names := make([]string, 0, len(eps))
+	for name := range eps {
+		names = append(names, name)
+	}
+	// stable ordering within the index
+	sort.Strings(names)
+
+	idx := util.NewIndex()
+
+	for _, name := range names {
+		ep := eps[name]
+		idx.InsertPatterns(name, ep.apiep.Method, ep.pathregs, len(ep.pcreregs) > 0)
+	}
+
+	return idx
 }
 
 func (r *staticRouter) isClosed() bool {
@@ -57,11 +114,30 @@ func (r *staticRouter) isClosed() bool {
 	}
 }
 
-func (r *staticRouter) Register(ep *api.Endpoint) error {
-	if err := api.Validate(ep); err != nil {
+// Register the endpoint with the transform rules and predicates of the options
+func (r *staticRouter) Register(ep *api.Endpoint, opts ...router.RegisterOption) error {
+	options := router.NewRegisterOptions(opts...)
+
+	e, err := r.compile(ep, options.Rules, options.Predicates)
+	if err != nil {
 		return err
 	}
 
+	r.update(func(eps map[string]*endpoint) {
+		eps[ep.Name] = e
+	})
+	return nil
+}
+
+// compile validates the endpoint and compiles its host and path matchers and predicates
+func (r *staticRouter) compile(ep *api.Endpoint, rules *transform.Rules, preds predicate.Predicates) (*endpoint, error) {
+	if err := api.Validate(ep); err != nil {
+		return nil, err
+	}
+	if err := preds.Compile(); err != nil {
+		return nil, err
+	}
+
 	var pathregs []util.Pattern
 	var hostregs []*regexp.Regexp
 	var pcreregs []*regexp.Regexp
@@ -72,7 +148,7 @@ func (r *staticRouter) Register(ep *api.Endpoint) error {
 		}
 		hostreg, err := regexp.CompilePOSIX(h)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		hostregs = append(hostregs, hostreg)
 	}
@@ -91,37 +167,38 @@ func (r *staticRouter) Register(ep *api.Endpoint) error {
 
 		rule, err := util.Parse(p)
 		if err != nil && !pcreok {
-			return err
+			return nil, err
 		} else if err != nil && pcreok {
 			continue
 		}
 
 		tpl := rule.Compile()
-		pathreg, err := util.NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, "")
+		pathreg, err := util.NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, tpl.Verb,
+			util.AssumeColonVerbOpt(!r.opts.AllowColonFinalSegments))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		pathregs = append(pathregs, pathreg)
 	}
 
-	r.Lock()
-	r.eps[ep.Name] = &endpoint{
+	return &endpoint{
 		apiep:    ep,
 		pcreregs: pcreregs,
 		pathregs: pathregs,
 		hostregs: hostregs,
-	}
-	r.Unlock()
-	return nil
+		rules:    rules,
+
+		predicates: preds,
+	}, nil
 }
 
 func (r *staticRouter) Deregister(ep *api.Endpoint) error {
 	if err := api.Validate(ep); err != nil {
 		return err
 	}
-	r.Lock()
-	delete(r.eps, ep.Name)
-	r.Unlock()
+	r.update(func(eps map[string]*endpoint) {
+		delete(eps, ep.Name)
+	})
 	return nil
 }
 
@@ -139,16 +216,48 @@ func (r *staticRouter) Close() error {
 	return nil
 }
 
-func (r *staticRouter) Endpoint(req *http.Request) (*api.Service, error) {
-	ep, err := r.endpoint(req)
+func (r *staticRouter) Routes() []*router.Route {
+	t := r.table()
+
+	names := make([]string, 0, len(t.eps))
+	for name := range t.eps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	routes := make([]*router.Route, 0, len(names))
+
+	for _, name := range names {
+		ep := t.eps[name]
+		epf := strings.Split(ep.apiep.Name, ".")
+
+		route := &router.Route{
+			Service:  epf[0],
+			Endpoint: ep.apiep,
+		}
+		for _, pathreg := range ep.pathregs {
+			route.Templates = append(route.Templates, pathreg.String())
+		}
+		for _, pathreg := range ep.pcreregs {
+			route.Templates = append(route.Templates, pathreg.String())
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+func (r *staticRouter) Endpoint(req *http.Request) (*api.Service, *http.Request, error) {
+	ep, rreq, err := r.endpoint(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	epf := strings.Split(ep.apiep.Name, ".")
 	services, err := r.opts.Registry.GetService(epf[0])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// hack for stream endpoint
@@ -176,7 +285,7 @@ func (r *staticRouter) Endpoint(req *http.Request) (*api.Service, error) {
 		Name: epf[0],
 		Endpoint: &api.Endpoint{
 			Name:    strings.Join(epf[1:], "."),
-			Handler: "rpc",
+			Handler: ep.apiep.Handler,
 			Host:    ep.apiep.Host,
 			Method:  ep.apiep.Method,
 			Path:    ep.apiep.Path,
@@ -186,130 +295,213 @@ func (r *staticRouter) Endpoint(req *http.Request) (*api.Service, error) {
 		Services: services,
 	}
 
-	return svc, nil
+	return svc, rreq, nil
 }
 
-func (r *staticRouter) endpoint(req *http.Request) (*endpoint, error) {
+func (r *staticRouter) endpoint(req *http.Request) (*endpoint, *http.Request, error) {
 	if r.isClosed() {
-		return nil, errors.New("router closed")
+		return nil, nil, errors.New("router closed")
 	}
 
-	r.RLock()
-	defer r.RUnlock()
+	t := r.table()
+	path, verb := util.SplitPath(req.URL.Path)
 
-	var idx int
-	if len(req.URL.Path) > 0 && req.URL.Path != "/" {
-		idx = 1
-	}
-	path := strings.Split(req.URL.Path[idx:], "/")
+	// only check the endpoints which could match
+	return r.match(t, t.idx.Lookup(req.Method, path), req, path, verb)
+}
+
+// match returns the first of the endpoints matching the request and a copy
+// of the request with the path variables and transform rules of the endpoint
+func (r *staticRouter) match(t *table, names []string, req *http.Request, path []string, verb string) (*endpoint, *http.Request, error) {
 	// use the first match
 	// TODO: weighted matching
-
-	for _, ep := range r.eps {
-		var mMatch, hMatch, pMatch bool
-
-		// 1. try method
-		for _, m := range ep.apiep.Method {
-			if m == req.Method {
-				mMatch = true
-				break
-			}
+	for _, name := range names {
+		ep, ok := t.eps[name]
+		if !ok {
+			continue
 		}
-		if !mMatch {
+
+		stage, _, matches := check(ep, req, path, verb)
+		if len(stage) > 0 {
 			continue
 		}
-		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-			logger.Debugf("api method match %s", req.Method)
+
+		// TODO: Percentage traffic
+
+		// we got here, so its a match
+		return ep, util.Annotate(req, matches, ep.apiep.Body, ep.rules), nil
+	}
+
+	// no match
+	return nil, nil, fmt.Errorf("endpoint not found for %v", req.URL)
+}
+
+// check runs the match stages for an endpoint without modifying the request.
+// It returns the stage the endpoint was rejected at or the matching pattern
+// and the variables it captured.
+func check(ep *endpoint, req *http.Request, path []string, verb string) (string, string, map[string]string) {
+	var mMatch, hMatch bool
+
+	// 1. try method
+	for _, m := range ep.apiep.Method {
+		if m == req.Method {
+			mMatch = true
+			break
 		}
+	}
+	if !mMatch {
+		return util.StageMethod, "", nil
+	}
+	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+		logger.Debugf("api method match %s", req.Method)
+	}
 
-		// 2. try host
-		if len(ep.apiep.Host) == 0 {
-			hMatch = true
-		} else {
-			for idx, h := range ep.apiep.Host {
-				if h == "" || h == "*" {
+	// 2. try host
+	if len(ep.apiep.Host) == 0 {
+		hMatch = true
+	} else {
+		for idx, h := range ep.apiep.Host {
+			if h == "" || h == "*" {
+				hMatch = true
+				break
+			} else {
+				if ep.hostregs[idx].MatchString(req.URL.Host) {
 					hMatch = true
 					break
-				} else {
-					if ep.hostregs[idx].MatchString(req.URL.Host) {
-						hMatch = true
-						break
-					}
 				}
 			}
 		}
-		if !hMatch {
+	}
+	if !hMatch {
+		return util.StageHost, "", nil
+	}
+	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+		logger.Debugf("api host match %s", req.URL.Host)
+	}
+
+	// 3. try google.api path
+	for _, pathreg := range ep.pathregs {
+		matches, err := pathreg.Match(path, verb)
+		if err != nil {
+			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				logger.Debugf("api gpath not match %s != %v", path, pathreg)
+			}
 			continue
 		}
 		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-			logger.Debugf("api host match %s", req.URL.Host)
+			logger.Debugf("api gpath match %s = %v", path, pathreg)
 		}
+		// 5. try the predicates
+		if !ep.predicates.Match(req) {
+			return util.StagePredicate, "", nil
+		}
+		return "", pathreg.String(), matches
+	}
 
-		// 3. try google.api path
-		for _, pathreg := range ep.pathregs {
-			matches, err := pathreg.Match(path, "")
-			if err != nil {
-				if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-					logger.Debugf("api gpath not match %s != %v", path, pathreg)
-				}
-				continue
-			}
+	// 4. try path via pcre path matching
+	for _, pathreg := range ep.pcreregs {
+		if !pathreg.MatchString(req.URL.Path) {
 			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-				logger.Debugf("api gpath match %s = %v", path, pathreg)
-			}
-			pMatch = true
-			ctx := req.Context()
-			md, ok := metadata.FromContext(ctx)
-			if !ok {
-				md = make(metadata.Metadata)
+				logger.Debugf("api pcre path not match %s != %v", req.URL.Path, pathreg)
 			}
-			for k, v := range matches {
-				md[fmt.Sprintf("x-api-field-%s", k)] = v
-			}
-			md["x-api-body"] = ep.apiep.Body
-			*req = *req.Clone(metadata.NewContext(ctx, md))
-			break
+			continue
+		}
+		if !ep.predicates.Match(req) {
+			return util.StagePredicate, "", nil
 		}
+		return "", pathreg.String(), nil
+	}
 
-		if !pMatch {
-			// 4. try path via pcre path matching
-			for _, pathreg := range ep.pcreregs {
-				if !pathreg.MatchString(req.URL.Path) {
-					if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-						logger.Debugf("api pcre path not match %s != %v", req.URL.Path, pathreg)
-					}
-					continue
-				}
-				pMatch = true
-				break
-			}
+	// rejected by the last stage we tried
+	if len(ep.pcreregs) > 0 {
+		return util.StagePCRE, "", nil
+	}
+	return util.StageGPath, "", nil
+}
+
+// Explain describes how the request would be routed
+func (r *staticRouter) Explain(req *http.Request) *router.Explanation {
+	exp := &router.Explanation{
+		Method: req.Method,
+		Host:   req.URL.Host,
+		Path:   req.URL.Path,
+	}
+
+	if r.isClosed() {
+		exp.Error = "router closed"
+		return exp
+	}
+
+	t := r.table()
+	path, verb := util.SplitPath(req.URL.Path)
+
+	// the indexed endpoints in the order they're tried then everything else
+	names := t.idx.Lookup(req.Method, path)
+	indexed := make(map[string]bool, len(names))
+	for _, name := range names {
+		indexed[name] = true
+	}
+	var rest []string
+	for name := range t.eps {
+		if !indexed[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	for _, name := range append(names, rest...) {
+		ep := t.eps[name]
+		epf := strings.Split(ep.apiep.Name, ".")
+
+		c := &router.Candidate{
+			Service:  epf[0],
+			Endpoint: strings.Join(epf[1:], "."),
+			Handler:  ep.apiep.Handler,
+			Method:   ep.apiep.Method,
+			Host:     ep.apiep.Host,
+			Path:     ep.apiep.Path,
+			Indexed:  indexed[name],
 		}
+		c.Rejected, c.Pattern, c.Vars = check(ep, req, path, verb)
 
-		if !pMatch {
-			continue
+		if len(c.Rejected) == 0 {
+			if exp.Match == nil {
+				exp.Match = c
+			} else {
+				c.Rejected = router.StageShadowed
+			}
 		}
-		// TODO: Percentage traffic
 
-		// we got here, so its a match
-		return ep, nil
+		exp.Candidates = append(exp.Candidates, c)
 	}
 
-	// no match
-	return nil, fmt.Errorf("endpoint not found for %v", req.URL)
+	if exp.Match == nil {
+		exp.Error = fmt.Sprintf("endpoint not found for %v", req.URL)
+	}
+
+	return exp
 }
 
-func (r *staticRouter) Route(req *http.Request) (*api.Service, error) {
+func (r *staticRouter) Route(req *http.Request) (*api.Service, *http.Request, error) {
 	if r.isClosed() {
-		return nil, errors.New("router closed")
+		return nil, nil, errors.New("router closed")
 	}
 
 	// try get an endpoint
-	ep, err := r.Endpoint(req)
+	ep, rreq, err := r.Endpoint(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return ep, nil
+	return ep, rreq, nil
+}
+
+// Resolve fails as static routes are only ever matched by their endpoints
+func (r *staticRouter) Resolve(req *http.Request) (*api.Service, *http.Request, error) {
+	if r.isClosed() {
+		return nil, nil, errors.New("router closed")
+	}
+	return nil, nil, fmt.Errorf("endpoint not found for %v", req.URL)
 }
 
 func NewRouter(opts ...router.Option) *staticRouter {
@@ -317,9 +509,21 @@ func NewRouter(opts ...router.Option) *staticRouter {
 	r := &staticRouter{
 		exit: make(chan bool),
 		opts: options,
-		eps:  make(map[string]*endpoint),
 	}
-	//go r.watch()
+	r.tbl.Store(&table{
+		eps: make(map[string]*endpoint),
+		idx: util.NewIndex(),
+	})
+
+	// load the routes file and watch for changes
+	if len(options.RoutesFile) > 0 {
+		if err := r.Load(options.RoutesFile); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("unable to load routes: %v", err)
+			}
+		}
+		go r.watch()
+	}
 	//go r.refresh()
 	return r
 }
@@ -0,0 +1,34 @@
+package router
+
+import (
+	"time"
+)
+
+// Monitor is implemented by routers which sync their routes from the registry
+type Monitor interface {
+	// Stats returns how up to date the routes are
+	Stats() *Stats
+}
+
+// Stats describe the freshness of a router's view of the registry
+type Stats struct {
+	// Connected is whether the registry watcher is running
+	Connected bool `json:"connected"`
+	// LastSync is the time of the last full resync
+	LastSync time.Time `json:"last_sync"`
+	// LastEvent is the time of the last watch event applied
+	LastEvent time.Time `json:"last_event"`
+	// Staleness is how long changes may have been missed for,
+	// the time since the watcher disconnected or the last sync
+	Staleness time.Duration `json:"staleness"`
+	Events    uint64        `json:"events"`
+	Resyncs   uint64        `json:"resyncs"`
+	// Reconnects counts the watcher disconnects
+	Reconnects uint64 `json:"reconnects"`
+	// Errors counts failed watches and resyncs
+	Errors uint64 `json:"errors"`
+	// Domains counts the registry domains with services
+	Domains   int `json:"domains"`
+	Services  int `json:"services"`
+	Endpoints int `json:"endpoints"`
+}
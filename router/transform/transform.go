@@ -0,0 +1,270 @@
+// Package transform provides declarative request and response rewriting for routes
+package transform
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/micro/micro/v3/service/context/metadata"
+)
+
+const (
+	// MetadataKey is the endpoint metadata key holding the json encoded rules
+	MetadataKey = "transform"
+	// BasePathHeader is sent to the backend with any prefix stripped from the path
+	BasePathHeader = "X-Micro-Web-Base-Path"
+)
+
+// Rule types
+const (
+	// SetHeader sets header Name to Value
+	SetHeader = "set_header"
+	// RenameHeader renames header Name to To
+	RenameHeader = "rename_header"
+	// RemoveHeader deletes header Name
+	RemoveHeader = "remove_header"
+	// SetMetadata sets the rpc metadata Name to Value
+	SetMetadata = "set_metadata"
+	// StripPrefix removes Value from the start of the path, or the first segment when blank
+	StripPrefix = "strip_prefix"
+	// ReplacePath replaces matches of Pattern in the path with To
+	ReplacePath = "replace_path"
+)
+
+// Rule is a single transformation
+type Rule struct {
+	Type    string `json:"type" yaml:"type"`
+	Name    string `json:"name,omitempty" yaml:"name"`
+	Value   string `json:"value,omitempty" yaml:"value"`
+	To      string `json:"to,omitempty" yaml:"to"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// Rules are the transformations applied to a route
+type Rules struct {
+	Request  []*Rule `json:"request,omitempty" yaml:"request"`
+	Response []*Rule `json:"response,omitempty" yaml:"response"`
+}
+
+type rulesKey struct{}
+
+// Decode parses and compiles json encoded rules
+func Decode(s string) (*Rules, error) {
+	rules := new(Rules)
+	if err := json.Unmarshal([]byte(s), rules); err != nil {
+		return nil, err
+	}
+	if err := rules.Compile(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Compile validates the rules and compiles any patterns
+func (r *Rules) Compile() error {
+	for _, rule := range r.Request {
+		switch rule.Type {
+		case SetHeader, RemoveHeader, SetMetadata:
+			if len(rule.Name) == 0 {
+				return fmt.Errorf("%s requires a name", rule.Type)
+			}
+		case RenameHeader:
+			if len(rule.Name) == 0 || len(rule.To) == 0 {
+				return fmt.Errorf("%s requires a name and to", rule.Type)
+			}
+		case StripPrefix:
+		case ReplacePath:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.re = re
+		default:
+			return fmt.Errorf("unknown request rule %q", rule.Type)
+		}
+	}
+
+	for _, rule := range r.Response {
+		switch rule.Type {
+		case SetHeader, RemoveHeader:
+			if len(rule.Name) == 0 {
+				return fmt.Errorf("%s requires a name", rule.Type)
+			}
+		case RenameHeader:
+			if len(rule.Name) == 0 || len(rule.To) == 0 {
+				return fmt.Errorf("%s requires a name and to", rule.Type)
+			}
+		default:
+			return fmt.Errorf("unknown response rule %q", rule.Type)
+		}
+	}
+
+	return nil
+}
+
+// Apply transforms the request and returns a writer which transforms the response.
+// It's safe to call on nil rules.
+func (r *Rules) Apply(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, *http.Request) {
+	if r == nil {
+		return w, req
+	}
+
+	if len(r.Request) > 0 {
+		req = r.request(req)
+	}
+
+	if len(r.Response) > 0 {
+		w = &responseWriter{ResponseWriter: w, rules: r.Response}
+	}
+
+	return w, req
+}
+
+func (r *Rules) request(req *http.Request) *http.Request {
+	ctx := req.Context()
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		md = make(metadata.Metadata)
+	}
+
+	req = req.Clone(ctx)
+	path := req.URL.Path
+	var base string
+
+	for _, rule := range r.Request {
+		switch rule.Type {
+		case SetHeader:
+			req.Header.Set(rule.Name, rule.Value)
+		case RenameHeader:
+			renameHeader(req.Header, rule.Name, rule.To)
+		case RemoveHeader:
+			req.Header.Del(rule.Name)
+		case SetMetadata:
+			md[rule.Name] = rule.Value
+		case StripPrefix:
+			prefix := rule.Value
+			if len(prefix) == 0 {
+				prefix = firstSegment(path)
+			}
+			prefix = strings.TrimSuffix(prefix, "/")
+			if len(prefix) == 0 || (path != prefix && !strings.HasPrefix(path, prefix+"/")) {
+				continue
+			}
+			path = strings.TrimPrefix(path, prefix)
+			base += prefix
+		case ReplacePath:
+			path = rule.re.ReplaceAllString(path, rule.To)
+		}
+	}
+
+	if len(path) == 0 || path[0] != '/' {
+		path = "/" + path
+	}
+
+	if path != req.URL.Path {
+		req.URL.Path = path
+		req.URL.RawPath = ""
+		req.RequestURI = req.URL.RequestURI()
+	}
+
+	if len(base) > 0 {
+		req.Header.Set(BasePathHeader, base)
+		md[BasePathHeader] = base
+	}
+
+	return req.WithContext(metadata.NewContext(ctx, md))
+}
+
+// responseWriter applies the response rules before the headers are written
+type responseWriter struct {
+	http.ResponseWriter
+	rules []*Rule
+	done  bool
+}
+
+func (w *responseWriter) apply() {
+	if w.done {
+		return
+	}
+	w.done = true
+
+	h := w.Header()
+	for _, rule := range w.rules {
+		switch rule.Type {
+		case SetHeader:
+			h.Set(rule.Name, rule.Value)
+		case RenameHeader:
+			renameHeader(h, rule.Name, rule.To)
+		case RemoveHeader:
+			h.Del(rule.Name)
+		}
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.apply()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	w.apply()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// NewContext returns a context carrying the route rules
+func NewContext(ctx context.Context, r *Rules) context.Context {
+	return context.WithValue(ctx, rulesKey{}, r)
+}
+
+// FromContext returns the route rules, nil if there are none
+func FromContext(ctx context.Context) *Rules {
+	r, _ := ctx.Value(rulesKey{}).(*Rules)
+	return r
+}
+
+func renameHeader(h http.Header, from, to string) {
+	vals := h.Values(from)
+	if len(vals) == 0 {
+		return
+	}
+	h.Del(from)
+	for _, v := range vals {
+		h.Add(to, v)
+	}
+}
+
+// firstSegment returns the first path segment e.g /greeter for /greeter/foo
+func firstSegment(path string) string {
+	p := strings.TrimPrefix(path, "/")
+	if idx := strings.IndexRune(p, '/'); idx >= 0 {
+		p = p[:idx]
+	}
+	if len(p) == 0 {
+		return ""
+	}
+	return "/" + p
+}
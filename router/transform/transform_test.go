@@ -0,0 +1,123 @@
+package transform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro/micro/v3/service/context/metadata"
+)
+
+func TestDecode(t *testing.T) {
+	testData := []struct {
+		rules string
+		valid bool
+	}{
+		{`{"request":[{"type":"strip_prefix"}]}`, true},
+		{`{"request":[{"type":"replace_path","pattern":"^/v1/(.*)$","to":"/$1"}]}`, true},
+		{`{"request":[{"type":"replace_path","pattern":"("}]}`, false},
+		{`{"request":[{"type":"set_header"}]}`, false},
+		{`{"request":[{"type":"unknown"}]}`, false},
+		{`{"response":[{"type":"set_metadata","name":"foo"}]}`, false},
+		{`{"response":[{"type":"rename_header","name":"Server"}]}`, false},
+		{`not json`, false},
+	}
+
+	for _, d := range testData {
+		_, err := Decode(d.rules)
+		if d.valid && err != nil {
+			t.Errorf("Expected %s to be valid got %v", d.rules, err)
+		} else if !d.valid && err == nil {
+			t.Errorf("Expected %s to be invalid", d.rules)
+		}
+	}
+}
+
+func TestRequest(t *testing.T) {
+	testData := []struct {
+		name   string
+		rules  string
+		path   string
+		expect string
+		base   string
+	}{
+		{"strip service", `{"request":[{"type":"strip_prefix"}]}`, "/greeter/foo", "/foo", "/greeter"},
+		{"strip to root", `{"request":[{"type":"strip_prefix"}]}`, "/greeter", "/", "/greeter"},
+		{"strip literal", `{"request":[{"type":"strip_prefix","value":"/api/v1/"}]}`, "/api/v1/users", "/users", "/api/v1"},
+		{"strip mismatch", `{"request":[{"type":"strip_prefix","value":"/api"}]}`, "/apiary", "/apiary", ""},
+		{"regex", `{"request":[{"type":"replace_path","pattern":"^/v([0-9]+)/(.*)$","to":"/$2/version/$1"}]}`, "/v2/foo", "/foo/version/2", ""},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			rules, err := Decode(d.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, req := rules.Apply(httptest.NewRecorder(), httptest.NewRequest("GET", d.path, nil))
+			if req.URL.Path != d.expect {
+				t.Fatalf("Expected path %s got %s", d.expect, req.URL.Path)
+			}
+			if b := req.Header.Get(BasePathHeader); b != d.base {
+				t.Fatalf("Expected base path %q got %q", d.base, b)
+			}
+		})
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	rules, err := Decode(`{
+		"request": [
+			{"type": "rename_header", "name": "X-Token", "to": "Authorization"},
+			{"type": "remove_header", "name": "Cookie"},
+			{"type": "set_header", "name": "X-Env", "value": "prod"},
+			{"type": "set_metadata", "name": "Tenant", "value": "acme"}
+		],
+		"response": [
+			{"type": "remove_header", "name": "Server"},
+			{"type": "rename_header", "name": "X-Internal-Id", "to": "X-Request-Id"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/foo", nil)
+	r.Header.Set("X-Token", "Bearer abc")
+	r.Header.Set("Cookie", "secret=1")
+
+	rec := httptest.NewRecorder()
+	w, req := rules.Apply(rec, r)
+
+	if v := req.Header.Get("Authorization"); v != "Bearer abc" {
+		t.Fatalf("Expected renamed header got %q", v)
+	}
+	if v := req.Header.Get("X-Token"); len(v) > 0 {
+		t.Fatalf("Expected original header to be removed got %q", v)
+	}
+	if v := req.Header.Get("Cookie"); len(v) > 0 {
+		t.Fatalf("Expected cookie to be removed got %q", v)
+	}
+	if v := req.Header.Get("X-Env"); v != "prod" {
+		t.Fatalf("Expected header to be set got %q", v)
+	}
+	if v, _ := metadata.Get(req.Context(), "Tenant"); v != "acme" {
+		t.Fatalf("Expected metadata to be set got %q", v)
+	}
+	// the original request is left untouched
+	if v := r.Header.Get("X-Token"); v != "Bearer abc" {
+		t.Fatalf("Expected original request to be unchanged got %q", v)
+	}
+
+	w.Header().Set("Server", "backend/1.0")
+	w.Header().Set("X-Internal-Id", "123")
+	w.WriteHeader(http.StatusOK)
+
+	if v := rec.Header().Get("Server"); len(v) > 0 {
+		t.Fatalf("Expected response header to be removed got %q", v)
+	}
+	if v := rec.Header().Get("X-Request-Id"); v != "123" {
+		t.Fatalf("Expected response header to be renamed got %q", v)
+	}
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/micro-community/micro-webui/router/transform"
+	"github.com/micro/micro/v3/service/context/metadata"
+)
+
+// Vars are the path variables an endpoint matched and where its body maps to
+type Vars struct {
+	// Fields are the path variables by the field path named in the template
+	Fields map[string]string
+	// Body is the body selector of the endpoint, "*" for the whole message
+	Body string
+}
+
+type varsKey struct{}
+
+// NewVarsContext returns a context carrying the matched vars
+func NewVarsContext(ctx context.Context, v *Vars) context.Context {
+	return context.WithValue(ctx, varsKey{}, v)
+}
+
+// VarsFromContext returns the vars the endpoint of the request matched
+func VarsFromContext(ctx context.Context) (*Vars, bool) {
+	v, ok := ctx.Value(varsKey{}).(*Vars)
+	return v, ok
+}
+
+// Annotate returns a copy of the request carrying the path variables matched
+// via google.api path, the body selector and the transform rules of the endpoint
+func Annotate(req *http.Request, matches map[string]string, body string, rules *transform.Rules) *http.Request {
+	ctx := req.Context()
+
+	if matches != nil {
+		md, ok := metadata.FromContext(ctx)
+		if !ok {
+			md = make(metadata.Metadata)
+		}
+		for k, v := range matches {
+			md[fmt.Sprintf("x-api-field-%s", k)] = v
+		}
+		md["x-api-body"] = body
+		ctx = metadata.NewContext(ctx, md)
+		// metadata title cases the keys so the names are kept as matched too
+		ctx = NewVarsContext(ctx, &Vars{Fields: matches, Body: body})
+	}
+
+	if rules != nil {
+		ctx = transform.NewContext(ctx, rules)
+	}
+
+	if ctx == req.Context() {
+		return req
+	}
+	return req.WithContext(ctx)
+}
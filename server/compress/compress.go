@@ -0,0 +1,321 @@
+// Package compress provides negotiated gzip, brotli and zstd response compression
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/micro-community/micro-webui/server"
+)
+
+// encoder is implemented by all the supported compressors
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var (
+	pools = map[string]*sync.Pool{
+		Gzip: {New: func() interface{} {
+			return gzip.NewWriter(nil)
+		}},
+		Brotli: {New: func() interface{} {
+			return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+		}},
+		Zstd: {New: func() interface{} {
+			e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			return e
+		}},
+	}
+)
+
+// NewWrapper returns a server.Wrapper which compresses responses
+func NewWrapper(opts ...Option) server.Wrapper {
+	options := NewOptions(opts...)
+
+	return func(h http.Handler) http.Handler {
+		return &compressHandler{
+			opts:    options,
+			handler: h,
+		}
+	}
+}
+
+type compressHandler struct {
+	opts    Options
+	handler http.Handler
+}
+
+func (c *compressHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// upgrades, partial content and bodiless requests are passed through untouched
+	if isUpgrade(r) || r.Method == "HEAD" || len(r.Header.Get("Range")) > 0 {
+		c.handler.ServeHTTP(w, r)
+		return
+	}
+
+	cw := &compressWriter{
+		ResponseWriter: w,
+		opts:           &c.opts,
+		encoding:       negotiate(r.Header.Get("Accept-Encoding"), c.opts.Encodings),
+	}
+	defer cw.Close()
+
+	c.handler.ServeHTTP(cw, r)
+}
+
+// compressWriter buffers the response until it knows whether it's worth compressing
+type compressWriter struct {
+	http.ResponseWriter
+	opts *Options
+
+	// negotiated encoding, blank if the client accepts none
+	encoding string
+	status   int
+	buf      []byte
+	// committed is set once the headers have been written
+	committed bool
+	enc       encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status != 0 || cw.committed {
+		return
+	}
+
+	cw.status = code
+
+	// no body means nothing to compress
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.commit(false)
+		return
+	}
+
+	// known to be too small
+	if cl, err := strconv.Atoi(cw.Header().Get("Content-Length")); err == nil && cl < cw.opts.MinSize {
+		cw.commit(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if cw.committed {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.opts.MinSize {
+		if err := cw.commit(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// commit decides on the encoding, writes the headers and any buffered body
+func (cw *compressWriter) commit(large bool) error {
+	cw.committed = true
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	h := cw.Header()
+
+	// sniff the content type now, net/http would otherwise sniff the compressed bytes
+	if _, ok := h["Content-Type"]; !ok && len(cw.buf) > 0 && len(h.Get("Content-Encoding")) == 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.compressible() {
+		h.Add("Vary", "Accept-Encoding")
+
+		if large && len(cw.encoding) > 0 {
+			cw.enc = pools[cw.encoding].Get().(encoder)
+			cw.enc.Reset(cw.ResponseWriter)
+			h.Del("Content-Length")
+			h.Set("Content-Encoding", cw.encoding)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+
+	return err
+}
+
+// compressible checks the response headers against the allowlist
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+
+	// already encoded upstream
+	if ce := h.Get("Content-Encoding"); len(ce) > 0 && ce != "identity" {
+		return false
+	}
+
+	ct, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	// event streams must be delivered as they're written
+	if cw.streaming() {
+		return false
+	}
+
+	for _, allowed := range cw.opts.ContentTypes {
+		if ct == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// streaming checks whether the response is a stream which must be delivered as it's written
+func (cw *compressWriter) streaming() bool {
+	ct, _, err := mime.ParseMediaType(cw.Header().Get("Content-Type"))
+	return err == nil && ct == "text/event-stream"
+}
+
+// Flush commits streams straight away, anything else stays buffered
+// until there's enough of it to decide whether to compress
+func (cw *compressWriter) Flush() {
+	if !cw.committed {
+		if !cw.streaming() {
+			return
+		}
+		cw.commit(false)
+	}
+
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// Close flushes anything left in the buffer and releases the encoder
+func (cw *compressWriter) Close() error {
+	if !cw.committed {
+		// nothing was written so leave it to net/http
+		if cw.status == 0 {
+			return nil
+		}
+		return cw.commit(false)
+	}
+
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+	cw.enc.Reset(nil)
+	pools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+
+	return err
+}
+
+// negotiate picks the best supported encoding from an Accept-Encoding header
+func negotiate(header string, supported []string) string {
+	if len(header) == 0 {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		q := 1.0
+		name := part
+		if idx := strings.IndexRune(part, ';'); idx >= 0 {
+			name = strings.TrimSpace(part[:idx])
+			param := strings.TrimSpace(part[idx+1:])
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					continue
+				}
+				q = v
+			}
+		}
+
+		accepted[strings.ToLower(name)] = q
+	}
+
+	var best string
+	var bestQ float64
+
+	for _, enc := range supported {
+		if _, ok := pools[enc]; !ok {
+			continue
+		}
+
+		q, ok := accepted[enc]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if !ok || q <= 0 {
+			continue
+		}
+
+		// ties go to the server preference
+		if q > bestQ {
+			best = enc
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+func isUpgrade(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.ToLower(strings.TrimSpace(v)) == "upgrade" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiate(t *testing.T) {
+	testData := []struct {
+		header string
+		expect string
+	}{
+		{"", ""},
+		{"gzip", Gzip},
+		{"gzip, br", Brotli},
+		{"gzip, br;q=0.5", Gzip},
+		{"gzip;q=0.5, zstd", Zstd},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", Brotli},
+		{"*;q=0.1, gzip", Gzip},
+		{"identity, deflate", ""},
+	}
+
+	for _, d := range testData {
+		if enc := negotiate(d.header, DefaultEncodings); enc != d.expect {
+			t.Errorf("Accept-Encoding %q: expected %q got %q", d.header, d.expect, enc)
+		}
+	}
+}
+
+func serve(h http.HandlerFunc, ae string, opts ...Option) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	if len(ae) > 0 {
+		r.Header.Set("Accept-Encoding", ae)
+	}
+	NewWrapper(opts...)(h).ServeHTTP(w, r)
+	return w
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"name":"go.micro.srv.greeter"}`, 100)
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "3100")
+		w.Write([]byte(body))
+	}
+
+	testData := []struct {
+		encoding string
+		decode   func(b []byte) ([]byte, error)
+	}{
+		{Gzip, func(b []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.ReadAll(r)
+		}},
+		{Brotli, func(b []byte) ([]byte, error) {
+			return ioutil.ReadAll(brotli.NewReader(bytes.NewReader(b)))
+		}},
+		{Zstd, func(b []byte) ([]byte, error) {
+			d, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer d.Close()
+			return d.DecodeAll(b, nil)
+		}},
+	}
+
+	for _, d := range testData {
+		t.Run(d.encoding, func(t *testing.T) {
+			w := serve(h, d.encoding)
+
+			if ce := w.Header().Get("Content-Encoding"); ce != d.encoding {
+				t.Fatalf("Expected Content-Encoding %s got %q", d.encoding, ce)
+			}
+			if cl := w.Header().Get("Content-Length"); len(cl) > 0 {
+				t.Fatalf("Expected Content-Length to be removed got %s", cl)
+			}
+			if v := w.Header().Get("Vary"); v != "Accept-Encoding" {
+				t.Fatalf("Expected Vary header got %q", v)
+			}
+
+			b, err := d.decode(w.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != body {
+				t.Fatalf("Unexpected body after decoding: %s", string(b))
+			}
+		})
+	}
+}
+
+func TestSkip(t *testing.T) {
+	large := strings.Repeat("a", 4096)
+
+	testData := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"Small response", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		}},
+		{"Content type not allowed", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(large))
+		}},
+		{"Event stream", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(large))
+		}},
+		{"Pre-compressed", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte(large))
+		}},
+		{"Not modified", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		}},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			w := serve(d.handler, "gzip, br")
+
+			if ce := w.Header().Get("Content-Encoding"); ce == Brotli {
+				t.Fatalf("Expected response not to be compressed")
+			}
+			if w.Body.Len() > 0 && w.Body.String() != large && w.Body.String() != `{}` {
+				t.Fatalf("Unexpected body %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+
+	NewWrapper()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(*compressWriter); ok {
+			t.Fatal("Expected upgrade to bypass compression")
+		}
+	})).ServeHTTP(w, r)
+}
+
+func TestFlush(t *testing.T) {
+	chunk := strings.Repeat(`{"name":"go.micro.srv.greeter"}`, 10)
+
+	// a backend which flushes each chunk so the proxy sees no content length
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		for i := 0; i < 10; i++ {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer backend.Close()
+
+	u, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(httputil.NewSingleHostReverseProxy(u).ServeHTTP, "gzip")
+
+	if ce := w.Header().Get("Content-Encoding"); ce != Gzip {
+		t.Fatalf("Expected the chunked response to be compressed got %q", ce)
+	}
+	r, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != strings.Repeat(chunk, 10) {
+		t.Fatalf("Unexpected body after decoding: %s", string(b))
+	}
+
+	// event streams are sent as soon as they're flushed
+	w = serve(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+
+		if rec := w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder); !rec.Flushed || rec.Body.Len() == 0 {
+			t.Fatal("Expected the event to be flushed")
+		}
+	}, "gzip")
+
+	if ce := w.Header().Get("Content-Encoding"); len(ce) > 0 {
+		t.Fatalf("Expected the event stream not to be compressed got %q", ce)
+	}
+}
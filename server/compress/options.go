@@ -0,0 +1,77 @@
+package compress
+
+const (
+	// Gzip content encoding
+	Gzip = "gzip"
+	// Brotli content encoding
+	Brotli = "br"
+	// Zstd content encoding
+	Zstd = "zstd"
+)
+
+var (
+	// DefaultMinSize is the smallest response body which is compressed
+	DefaultMinSize = 1024
+	// DefaultEncodings in order of server preference
+	DefaultEncodings = []string{Brotli, Zstd, Gzip}
+	// DefaultContentTypes which are worth compressing
+	DefaultContentTypes = []string{
+		"text/html",
+		"text/css",
+		"text/plain",
+		"text/javascript",
+		"text/xml",
+		"application/javascript",
+		"application/json",
+		"application/xml",
+		"image/svg+xml",
+	}
+)
+
+// Options for the compression wrapper
+type Options struct {
+	// MinSize is the response size below which we don't compress
+	MinSize int
+	// Encodings supported, in order of preference
+	Encodings []string
+	// ContentTypes allowed to be compressed
+	ContentTypes []string
+}
+
+type Option func(o *Options)
+
+// NewOptions returns initialized options
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		MinSize:      DefaultMinSize,
+		Encodings:    DefaultEncodings,
+		ContentTypes: DefaultContentTypes,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return options
+}
+
+// MinSize sets the minimum response size to compress
+func MinSize(n int) Option {
+	return func(o *Options) {
+		o.MinSize = n
+	}
+}
+
+// Encodings sets the supported encodings in order of preference
+func Encodings(e ...string) Option {
+	return func(o *Options) {
+		o.Encodings = e
+	}
+}
+
+// ContentTypes sets the content types which may be compressed
+func ContentTypes(ct ...string) Option {
+	return func(o *Options) {
+		o.ContentTypes = ct
+	}
+}
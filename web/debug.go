@@ -0,0 +1,138 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro-community/micro-webui/router"
+)
+
+// wantsJSON reports whether the client asked for a json response
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Content-Type"), "application/json") ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// DebugRouteHandler explains how the router would route a request
+// described by the method, host and path query parameters, made with
+// the headers, cookies and address of the caller
+func (s *srvWeb) DebugRouteHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	method := strings.ToUpper(q.Get("method"))
+	if len(method) == 0 {
+		method = "GET"
+	}
+	host := q.Get("host")
+	if len(host) == 0 {
+		host = r.Host
+	}
+	path := q.Get("path")
+
+	var exp *router.Explanation
+
+	if len(path) > 0 {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+
+		ex, ok := s.rt.(router.Explainer)
+		if !ok {
+			http.Error(w, "Router does not support explaining routes", http.StatusNotImplemented)
+			return
+		}
+
+		// build the request we're explaining, it's never served
+		req, err := http.NewRequestWithContext(r.Context(), method, "http://"+host+path, nil)
+		if err != nil {
+			http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// predicates match on the headers, cookies and address of the caller
+		req.Header = r.Header.Clone()
+		req.RemoteAddr = r.RemoteAddr
+
+		exp = ex.Explain(req)
+
+		// only the routes of the namespace are for the caller to see
+		if !scopeExplanation(exp, namespace.FromContext(r.Context())) {
+			http.Error(w, "Host routes outside of the namespace", http.StatusForbidden)
+			return
+		}
+	}
+
+	if wantsJSON(r) {
+		if exp == nil {
+			http.Error(w, "path is required", http.StatusBadRequest)
+			return
+		}
+		b, err := json.Marshal(exp)
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+		return
+	}
+
+	s.render(w, r, debugRouteTemplate, map[string]interface{}{
+		"Method":      method,
+		"Host":        host,
+		"Path":        path,
+		"Methods":     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		"Explanation": exp,
+	})
+}
+
+// scopeExplanation removes the candidates of other domains from the explanation,
+// returning false if the request is routed in another domain altogether
+func scopeExplanation(exp *router.Explanation, ns string) bool {
+	if len(exp.Domain) > 0 && exp.Domain != ns {
+		return false
+	}
+	if exp.Fallback != nil && len(exp.Fallback.Domain) > 0 && exp.Fallback.Domain != ns {
+		return false
+	}
+
+	candidates := make([]*router.Candidate, 0, len(exp.Candidates))
+	for _, c := range exp.Candidates {
+		if routeDomain(c.Domain) == ns {
+			candidates = append(candidates, c)
+		}
+	}
+	exp.Candidates = candidates
+
+	if exp.Match != nil && routeDomain(exp.Match.Domain) != ns {
+		exp.Match = nil
+		exp.Error = fmt.Sprintf("endpoint not found for %s", exp.Path)
+	}
+
+	return true
+}
+
+// RouterStatsHandler reports how up to date the routes are, which covers
+// every domain so it's only for the default namespace
+func (s *srvWeb) RouterStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if namespace.FromContext(r.Context()) != namespace.DefaultNamespace {
+		http.Error(w, namespace.ErrForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	m, ok := s.rt.(router.Monitor)
+	if !ok {
+		http.Error(w, "Router does not report stats", http.StatusNotImplemented)
+		return
+	}
+
+	b, err := json.Marshal(m.Stats())
+	if err != nil {
+		http.Error(w, "Error occurred:"+err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -1,8 +1,11 @@
 package web
 
 import (
+	"fmt"
+	"io/ioutil"
 	"strings"
 
+	"github.com/micro-community/micro-webui/resolver/pattern"
 	"github.com/micro/micro/v3/service"
 	"github.com/micro/micro/v3/service/config"
 	"github.com/urfave/cli/v2"
@@ -17,8 +20,37 @@ func ResolveContext(ctx *cli.Context) error {
 	if len(ctx.String("web_address")) > 0 {
 		Address = ctx.String("web_address")
 	}
-	if len(ctx.String("resolver")) > 0 {
-		Resolver = ctx.String("resolver")
+	if len(ctx.String("web_resolver")) > 0 {
+		Resolver = ctx.String("web_resolver")
+	}
+	if len(ctx.String("web_resolver_service_header")) > 0 {
+		ServiceHeader = ctx.String("web_resolver_service_header")
+	}
+	if ctx.IsSet("web_resolver_endpoint_header") {
+		EndpointHeader = ctx.String("web_resolver_endpoint_header")
+	}
+	if len(ctx.String("web_resolver_rules")) > 0 {
+		ResolverRulesFile = ctx.String("web_resolver_rules")
+	}
+	if Resolver == "pattern" {
+		if err := loadResolverRules(); err != nil {
+			return err
+		}
+	}
+	if len(ctx.String("web_routes_file")) > 0 {
+		RoutesFile = ctx.String("web_routes_file")
+	}
+	if domains := ctx.StringSlice("web_domains"); len(domains) > 0 {
+		Domains = domains
+	}
+	if ctx.IsSet("web_resync_interval") {
+		ResyncInterval = ctx.Duration("web_resync_interval")
+	}
+	if ctx.IsSet("web_client_history") {
+		ClientHistory = ctx.Int("web_client_history")
+	}
+	if ctx.Bool("web_rewrite_html") {
+		RewriteHTML = true
 	}
 	if len(ctx.String("type")) > 0 {
 		Type = ctx.String("type")
@@ -47,9 +79,49 @@ func Flags(options ...service.Option) []cli.Flag {
 		},
 		&cli.StringFlag{
 			Name:    "web_resolver",
-			Usage:   "Set the resolver to route to services e.g path, domain",
+			Usage:   "Set the resolver to route to services e.g path, vpath, rpc, host, subdomain, header, pattern",
 			EnvVars: []string{"MICRO_WEB_RESOLVER"},
 		},
+		&cli.StringFlag{
+			Name:    "web_resolver_service_header",
+			Usage:   "Set the header the header resolver reads the service from",
+			EnvVars: []string{"MICRO_WEB_RESOLVER_SERVICE_HEADER"},
+		},
+		&cli.StringFlag{
+			Name:    "web_resolver_endpoint_header",
+			Usage:   "Set the header the header resolver reads the endpoint from, empty to ignore it",
+			EnvVars: []string{"MICRO_WEB_RESOLVER_ENDPOINT_HEADER"},
+		},
+		&cli.StringFlag{
+			Name:    "web_resolver_rules",
+			Usage:   "Set a yaml or json file of rules for the pattern resolver",
+			EnvVars: []string{"MICRO_WEB_RESOLVER_RULES"},
+		},
+		&cli.StringFlag{
+			Name:    "web_routes_file",
+			Usage:   "Set a yaml or json file of static routes, reloaded on change",
+			EnvVars: []string{"MICRO_WEB_ROUTES_FILE"},
+		},
+		&cli.DurationFlag{
+			Name:    "web_resync_interval",
+			Usage:   "Set how often routes are fully resynced from the registry e.g 10m, a negative value disables it",
+			EnvVars: []string{"MICRO_WEB_RESYNC_INTERVAL"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "web_domains",
+			Usage:   "Set the registry domains to route to by subdomain e.g foo,bar or * for all",
+			EnvVars: []string{"MICRO_WEB_DOMAINS"},
+		},
+		&cli.IntFlag{
+			Name:    "web_client_history",
+			Usage:   "Set the number of calls kept in the client page history of each account, 0 disables it",
+			EnvVars: []string{"MICRO_WEB_CLIENT_HISTORY"},
+		},
+		&cli.BoolFlag{
+			Name:    "web_rewrite_html",
+			Usage:   "Rewrite absolute links in html served by web apps to include the base path",
+			EnvVars: []string{"MICRO_WEB_REWRITE_HTML"},
+		},
 		&cli.StringFlag{
 			Name:    "auth_login_url",
 			EnvVars: []string{"MICRO_AUTH_LOGIN_URL"},
@@ -60,6 +132,23 @@ func Flags(options ...service.Option) []cli.Flag {
 	return flags
 }
 
+// loadResolverRules validates the pattern resolver rules at startup
+func loadResolverRules() error {
+	if len(ResolverRulesFile) == 0 {
+		return fmt.Errorf("the pattern resolver requires --web_resolver_rules")
+	}
+	b, err := ioutil.ReadFile(ResolverRulesFile)
+	if err != nil {
+		return err
+	}
+	rules, err := pattern.ParseFile(ResolverRulesFile, b)
+	if err != nil {
+		return err
+	}
+	ResolverRules = rules
+	return nil
+}
+
 //ParseEnv from env
 func ParseEnv() {
 
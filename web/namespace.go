@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro/micro/v3/service/auth"
+)
+
+// withTenant scopes a dashboard handler to the namespace of the request,
+// the handler reads it with namespace.FromContext
+func (s *srvWeb) withTenant(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ns := s.nr.Resolve(r)
+
+		ctx := accountContext(r)
+		// the default namespace is open to anyone, like the dashboard without auth
+		if err := namespace.Authorize(ctx, ns, namespace.Public(namespace.DefaultNamespace)); err != nil {
+			code := http.StatusUnauthorized
+			if err == namespace.ErrForbidden {
+				code = http.StatusForbidden
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		// the namespace is ours to set, not the caller's
+		r.Header.Del(namespace.NamespaceKey)
+
+		h(w, r.WithContext(namespace.ContextWithNamespace(ctx, ns)))
+	}
+}
+
+// accountContext returns the request context with the account of its token
+func accountContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if _, ok := auth.AccountFromContext(ctx); ok {
+		return ctx
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), BearerScheme)
+	if c, err := r.Cookie(TokenCookieName); len(token) == 0 && err == nil {
+		token = c.Value
+	}
+	if len(token) == 0 {
+		return ctx
+	}
+
+	acc, err := auth.Inspect(token)
+	if err != nil {
+		return ctx
+	}
+
+	return auth.ContextWithAccount(ctx, acc)
+}
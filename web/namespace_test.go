@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro/micro/v3/service/auth"
+)
+
+func TestWithTenant(t *testing.T) {
+	testData := []struct {
+		name      string
+		namespace string
+		host      string
+		issuer    string
+		code      int
+		tenant    string
+	}{
+		{"default namespace is public", "micro", "localhost", "", http.StatusOK, "micro"},
+		{"domain without account", "domain", "foo.example.com", "", http.StatusUnauthorized, ""},
+		{"domain of another tenant", "domain", "foo.example.com", "bar", http.StatusForbidden, ""},
+		{"domain of the tenant", "domain", "foo.example.com", "foo", http.StatusOK, "foo"},
+		{"server account", "domain", "foo.example.com", "micro", http.StatusOK, "foo"},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			s := &srvWeb{nr: namespace.NewResolver("web", d.namespace)}
+
+			var tenant, header string
+			h := s.withTenant(func(w http.ResponseWriter, r *http.Request) {
+				tenant = namespace.FromContext(r.Context())
+				header = r.Header.Get(namespace.NamespaceKey)
+			})
+
+			req := httptest.NewRequest("GET", "http://"+d.host+"/services", nil)
+			// callers can't pick the namespace
+			req.Header.Set(namespace.NamespaceKey, "bar")
+			if len(d.issuer) > 0 {
+				req = req.WithContext(auth.ContextWithAccount(req.Context(), &auth.Account{ID: "user", Issuer: d.issuer}))
+			}
+
+			w := httptest.NewRecorder()
+			h(w, req)
+
+			if w.Code != d.code {
+				t.Fatalf("Expected status %d got %d", d.code, w.Code)
+			}
+			if tenant != d.tenant {
+				t.Fatalf("Expected namespace %q got %q", d.tenant, tenant)
+			}
+			if len(header) > 0 {
+				t.Fatalf("Expected the namespace header to be removed got %q", header)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/micro-community/micro-webui/helper/openapi"
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro/micro/v3/service/logger"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// OpenAPIHandler serves an OpenAPI document of the service named in the path,
+// or of every service in the namespace
+func (s *srvWeb) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	ns := namespace.FromContext(r.Context())
+	name := mux.Vars(r)["name"]
+
+	var services []*registry.Service
+	// services are versioned apart so the namespace has none
+	info := &openapi.Info{Title: ns + " services", Version: "latest"}
+
+	if len(name) > 0 {
+		sv, err := s.registry.GetService(name, registry.GetContext(r.Context()), registry.GetDomain(ns))
+		if err == registry.ErrNotFound || (err == nil && len(sv) == 0) {
+			http.Error(w, "Not found", 404)
+			return
+		} else if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		services = sv
+		info = &openapi.Info{Title: name, Version: sv[0].Version}
+	} else {
+		list, err := s.registry.ListServices(registry.ListContext(r.Context()), registry.ListDomain(ns))
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+
+		// listing doesn't always include the endpoints
+		seen := make(map[string]bool)
+		for _, l := range list {
+			if seen[l.Name] {
+				continue
+			}
+			seen[l.Name] = true
+
+			sv, err := s.registry.GetService(l.Name, registry.GetContext(r.Context()), registry.GetDomain(ns))
+			if err != nil {
+				if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+					logger.Debugf("Error getting service %s: %v", l.Name, err)
+				}
+				continue
+			}
+			services = append(services, sv...)
+		}
+	}
+
+	b, err := json.Marshal(openapi.Generate(info, services))
+	if err != nil {
+		http.Error(w, "Error occurred:"+err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// OpenAPIExplorerHandler renders an explorer of the OpenAPI document
+func (s *srvWeb) OpenAPIExplorerHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	url := "/openapi.json"
+	if len(name) > 0 {
+		url = "/service/" + name + "/openapi.json"
+	}
+
+	s.render(w, r, openapiTemplate, map[string]interface{}{
+		"Name": name,
+		"URL":  url,
+	})
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/micro-community/micro-webui/helper/openapi"
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/registry/memory"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(&registry.Service{
+		Name:      "foo",
+		Version:   "v1",
+		Nodes:     []*registry.Node{testNode("foo-1")},
+		Endpoints: []*registry.Endpoint{{Name: "Foo.Call"}},
+	})
+	reg.Register(&registry.Service{
+		Name:      "bar",
+		Version:   "v1",
+		Nodes:     []*registry.Node{testNode("bar-1")},
+		Endpoints: []*registry.Endpoint{{Name: "Bar.Call"}},
+	}, registry.RegisterDomain("tenant"))
+
+	s := &srvWeb{registry: reg}
+
+	testData := []struct {
+		name      string
+		namespace string
+		code      int
+		calls     int
+	}{
+		{"", registry.DefaultDomain, http.StatusOK, 1},
+		{"foo", registry.DefaultDomain, http.StatusOK, 1},
+		{"bar", registry.DefaultDomain, http.StatusNotFound, 0},
+		{"bar", "tenant", http.StatusOK, 1},
+	}
+
+	for _, d := range testData {
+		req := httptest.NewRequest("GET", "/openapi.json", nil)
+		req = req.WithContext(namespace.ContextWithNamespace(req.Context(), d.namespace))
+		if len(d.name) > 0 {
+			req = mux.SetURLVars(req, map[string]string{"name": d.name})
+		}
+
+		w := httptest.NewRecorder()
+		s.OpenAPIHandler(w, req)
+
+		if w.Code != d.code {
+			t.Fatalf("Expected status %d for %s in %s got %d", d.code, d.name, d.namespace, w.Code)
+		}
+		if d.code != http.StatusOK {
+			continue
+		}
+
+		var doc openapi.Document
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.OpenAPI != openapi.Version {
+			t.Fatalf("Expected openapi %s got %s", openapi.Version, doc.OpenAPI)
+		}
+		if calls := doc.Paths[openapi.RPCPath].Post.RequestBody.Content["application/json"].Schema.OneOf; len(calls) != d.calls {
+			t.Fatalf("Expected %d calls got %d", d.calls, len(calls))
+		}
+	}
+}
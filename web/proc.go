@@ -15,6 +15,7 @@ import (
 	"golang.org/x/net/publicsuffix"
 
 	utils "github.com/micro-community/micro-webui/helper/registry"
+	"github.com/micro-community/micro-webui/namespace"
 )
 
 type webService struct {
@@ -75,7 +76,9 @@ func (s *srvWeb) indexHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	services, err := s.registry.ListServices(registry.ListContext(r.Context()))
+	ns := namespace.FromContext(r.Context())
+
+	services, err := s.registry.ListServices(registry.ListContext(r.Context()), registry.ListDomain(ns))
 	if err != nil {
 		logger.Errorf("Error listing services: %v", err)
 	}
@@ -116,26 +119,44 @@ func (s *srvWeb) indexHandler(w http.ResponseWriter, r *http.Request) {
 	s.render(w, r, indexTemplate, data)
 }
 
+// serviceView is the detail page of a service
+type serviceView struct {
+	Name     string
+	Services []*registry.Service
+	// Diffs compare each version with the previous one
+	Diffs []*versionDiff
+	// History of nodes joining and leaving, oldest first
+	History []*serviceEvent
+}
+
 func (s *srvWeb) RegistryHandler(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	svc := vars["name"]
+	ns := namespace.FromContext(r.Context())
 
 	if len(svc) > 0 {
-		sv, err := s.registry.GetService(svc, registry.GetContext(r.Context()))
-		if err != nil {
+		sv, err := s.registry.GetService(svc, registry.GetContext(r.Context()), registry.GetDomain(ns))
+		if err == registry.ErrNotFound || (err == nil && len(sv) == 0) {
+			http.Error(w, "Not found", 404)
+			return
+		} else if err != nil {
 			http.Error(w, "Error occurred:"+err.Error(), 500)
 			return
 		}
 
-		if len(sv) == 0 {
-			http.Error(w, "Not found", 404)
-			return
+		view := &serviceView{
+			Name:     svc,
+			Services: sv,
+			Diffs:    diffVersions(sv),
+			History:  s.watcher.events(serviceNamespace(r), svc),
 		}
 
-		if r.Header.Get("Content-Type") == "application/json" {
+		if wantsJSON(r) {
 			b, err := json.Marshal(map[string]interface{}{
-				"services": s,
+				"services": view.Services,
+				"diffs":    view.Diffs,
+				"history":  view.History,
 			})
 			if err != nil {
 				http.Error(w, "Error occurred:"+err.Error(), 500)
@@ -146,18 +167,18 @@ func (s *srvWeb) RegistryHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		s.render(w, r, serviceTemplate, sv)
+		s.render(w, r, serviceTemplate, view)
 		return
 	}
 
-	services, err := s.registry.ListServices(registry.ListContext(r.Context()))
+	services, err := s.registry.ListServices(registry.ListContext(r.Context()), registry.ListDomain(ns))
 	if err != nil {
 		logger.Errorf("Error listing services: %v", err)
 	}
 
 	sort.Sort(utils.SortedServices{Services: services})
 
-	if r.Header.Get("Content-Type") == "application/json" {
+	if wantsJSON(r) {
 		b, err := json.Marshal(map[string]interface{}{
 			"services": services,
 		})
@@ -175,8 +196,9 @@ func (s *srvWeb) RegistryHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *srvWeb) CallHandler(w http.ResponseWriter, r *http.Request) {
+	ns := namespace.FromContext(r.Context())
 
-	services, err := s.registry.ListServices(registry.ListContext(r.Context()))
+	services, err := s.registry.ListServices(registry.ListContext(r.Context()), registry.ListDomain(ns))
 	if err != nil {
 		logger.Errorf("Error listing services: %v", err)
 	}
@@ -190,7 +212,7 @@ func (s *srvWeb) CallHandler(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		// lookup the endpoints otherwise
-		s, err := s.registry.GetService(service.Name, registry.GetContext(r.Context()))
+		s, err := s.registry.GetService(service.Name, registry.GetContext(r.Context()), registry.GetDomain(ns))
 		if err != nil {
 			continue
 		}
@@ -200,7 +222,7 @@ func (s *srvWeb) CallHandler(w http.ResponseWriter, r *http.Request) {
 		serviceMap[service.Name] = s[0].Endpoints
 	}
 
-	if r.Header.Get("Content-Type") == "application/json" {
+	if wantsJSON(r) {
 		b, err := json.Marshal(map[string]interface{}{
 			"services": services,
 		})
@@ -219,8 +241,9 @@ func (s *srvWeb) CallHandler(w http.ResponseWriter, r *http.Request) {
 
 func (s *srvWeb) render(w http.ResponseWriter, r *http.Request, tmpl string, data interface{}) {
 	t, err := template.New("template").Funcs(template.FuncMap{
-		"format": utils.Format,
-		"Title":  strings.Title,
+		"format":  utils.Format,
+		"example": utils.Example,
+		"Title":   strings.Title,
 		"First": func(s string) string {
 			if len(s) == 0 {
 				return s
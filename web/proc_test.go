@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/registry/memory"
+)
+
+func TestCallPage(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(&registry.Service{
+		Name:    "foo",
+		Version: "latest",
+		Nodes:   []*registry.Node{testNode("foo-1")},
+		Endpoints: []*registry.Endpoint{
+			{
+				Name: "Foo.Call",
+				Request: &registry.Value{Name: "Request", Type: "Request", Values: []*registry.Value{
+					{Name: "name", Type: "string"},
+				}},
+			},
+			{Name: "Foo.Ping"},
+		},
+	})
+
+	s := &srvWeb{registry: reg}
+
+	w := httptest.NewRecorder()
+	s.CallHandler(w, httptest.NewRequest("GET", "/client", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 got %d %s", w.Code, w.Body.String())
+	}
+
+	// the example and schema are embedded as javascript values
+	body := w.Body.String()
+	for _, expect := range []string{
+		`"example": "{\n  \"name\": \"\"\n}"`,
+		`"schema": {"name":"Request","type":"Request","values":[{"name":"name","type":"string","values":null}]}`,
+		`e_map["foo" + "/" + "Foo.Ping"] = {"example": "{}"`,
+	} {
+		if !strings.Contains(body, expect) {
+			t.Fatalf("Expected the page to contain %s", expect)
+		}
+	}
+}
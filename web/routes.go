@@ -0,0 +1,74 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// RoutesHandler lists the routes known to the router, optionally
+// filtered by the service and method query parameters
+func (s *srvWeb) RoutesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	service := q.Get("service")
+	method := strings.ToUpper(q.Get("method"))
+
+	ns := namespace.FromContext(r.Context())
+	routes := []*router.Route{}
+
+	for _, route := range s.rt.Routes() {
+		// only the routes of the namespace
+		if routeDomain(route.Domain) != ns {
+			continue
+		}
+		if len(service) > 0 && !strings.Contains(strings.ToLower(route.Service), strings.ToLower(service)) {
+			continue
+		}
+		if len(method) > 0 && !hasMethod(route.Endpoint.Method, method) {
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	if wantsJSON(r) {
+		b, err := json.Marshal(map[string]interface{}{
+			"routes": routes,
+		})
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+		return
+	}
+
+	s.render(w, r, routesTemplate, map[string]interface{}{
+		"Service": service,
+		"Method":  method,
+		"Methods": []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		"Routes":  routes,
+	})
+}
+
+// routeDomain returns the registry domain of a route, static routes call
+// services in the default domain
+func routeDomain(domain string) string {
+	if len(domain) == 0 {
+		return registry.DefaultDomain
+	}
+	return domain
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
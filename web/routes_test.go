@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro-community/micro-webui/router"
+	"github.com/micro/micro/v3/service/api"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// testRouter has routes in the default and tenant domains
+type testRouter struct {
+	router.Router
+	explain *router.Explanation
+	// the last request explained
+	req *http.Request
+}
+
+func (r *testRouter) Routes() []*router.Route {
+	return []*router.Route{
+		{Service: "static", Endpoint: &api.Endpoint{Name: "Static.Call"}},
+		{Service: "foo", Domain: registry.DefaultDomain, Endpoint: &api.Endpoint{Name: "Foo.Call"}},
+		{Service: "bar", Domain: "tenant", Endpoint: &api.Endpoint{Name: "Bar.Call"}},
+	}
+}
+
+func (r *testRouter) Explain(req *http.Request) *router.Explanation {
+	r.req = req
+	return r.explain
+}
+
+func TestRoutesHandlerNamespace(t *testing.T) {
+	s := &srvWeb{rt: &testRouter{}}
+
+	testData := []struct {
+		namespace string
+		services  []string
+	}{
+		{registry.DefaultDomain, []string{"static", "foo"}},
+		{"tenant", []string{"bar"}},
+	}
+
+	for _, d := range testData {
+		req := httptest.NewRequest("GET", "/routes", nil)
+		req.Header.Set("Accept", "application/json")
+		req = req.WithContext(namespace.ContextWithNamespace(req.Context(), d.namespace))
+
+		w := httptest.NewRecorder()
+		s.RoutesHandler(w, req)
+
+		var rsp struct {
+			Routes []*router.Route `json:"routes"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+
+		var services []string
+		for _, r := range rsp.Routes {
+			services = append(services, r.Service)
+		}
+		if len(services) != len(d.services) {
+			t.Fatalf("Expected routes %v in %s got %v", d.services, d.namespace, services)
+		}
+		for i := range services {
+			if services[i] != d.services[i] {
+				t.Fatalf("Expected routes %v in %s got %v", d.services, d.namespace, services)
+			}
+		}
+	}
+}
+
+func TestDebugRouteHandlerNamespace(t *testing.T) {
+	static := &router.Candidate{Service: "static", Layer: "static"}
+	bar := &router.Candidate{Service: "bar", Layer: "registry", Domain: "tenant"}
+
+	rt := &testRouter{}
+	s := &srvWeb{rt: rt}
+
+	explain := func(ns string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/debug/route?path=/bar", nil)
+		req.Header.Set("Accept", "application/json")
+		req = req.WithContext(namespace.ContextWithNamespace(req.Context(), ns))
+
+		w := httptest.NewRecorder()
+		s.DebugRouteHandler(w, req)
+		return w
+	}
+
+	// the host is routed in the tenant domain
+	rt.explain = &router.Explanation{Path: "/bar", Domain: "tenant", Candidates: []*router.Candidate{static, bar}, Match: static}
+
+	if w := explain(registry.DefaultDomain); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 got %d", w.Code)
+	}
+
+	w := explain("tenant")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 got %d", w.Code)
+	}
+
+	var exp router.Explanation
+	if err := json.Unmarshal(w.Body.Bytes(), &exp); err != nil {
+		t.Fatal(err)
+	}
+	if len(exp.Candidates) != 1 || exp.Candidates[0].Service != "bar" {
+		t.Fatalf("Expected only the tenant candidates got %+v", exp.Candidates)
+	}
+	if exp.Match != nil {
+		t.Fatalf("Expected the match of the default domain to be hidden got %+v", exp.Match)
+	}
+
+	// resolved to a service in another domain
+	rt.explain = &router.Explanation{Path: "/bar", Fallback: &router.Fallback{Service: "bar", Domain: "tenant"}}
+
+	if w := explain(registry.DefaultDomain); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 got %d", w.Code)
+	}
+}
+
+func TestDebugRouteHandlerCaller(t *testing.T) {
+	rt := &testRouter{explain: &router.Explanation{Path: "/bar"}}
+	s := &srvWeb{rt: rt}
+
+	req := httptest.NewRequest("GET", "/debug/route?method=post&path=/bar", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Group", "beta")
+	req.AddCookie(&http.Cookie{Name: "canary", Value: "1"})
+	req.RemoteAddr = "10.0.0.1:1234"
+	req = req.WithContext(namespace.ContextWithNamespace(req.Context(), registry.DefaultDomain))
+
+	w := httptest.NewRecorder()
+	s.DebugRouteHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 got %d", w.Code)
+	}
+
+	if rt.req.Method != "POST" || rt.req.URL.Path != "/bar" {
+		t.Fatalf("Expected POST /bar got %s %s", rt.req.Method, rt.req.URL.Path)
+	}
+	if v := rt.req.Header.Get("X-Group"); v != "beta" {
+		t.Fatalf("Expected the caller's header got %q", v)
+	}
+	if c, err := rt.req.Cookie("canary"); err != nil || c.Value != "1" {
+		t.Fatalf("Expected the caller's cookie got %v %v", c, err)
+	}
+	if rt.req.RemoteAddr != "10.0.0.1:1234" {
+		t.Fatalf("Expected the caller's address got %s", rt.req.RemoteAddr)
+	}
+}
@@ -0,0 +1,291 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/micro-community/micro-webui/handler"
+	"github.com/micro/micro/v3/service/auth"
+	"github.com/micro/micro/v3/service/logger"
+	"github.com/micro/micro/v3/service/store"
+)
+
+var (
+	// savedPrefix for keys of saved requests written to the store
+	savedPrefix = "web/saved/"
+	// historyPrefix for keys of the call history written to the store
+	historyPrefix = "web/history/"
+	// maxHistoryResponse is the largest response kept in the history
+	maxHistoryResponse = 64 * 1024
+)
+
+// call is a request made from the client page
+type call struct {
+	Service  string            `json:"service"`
+	Endpoint string            `json:"endpoint"`
+	Address  string            `json:"address,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Request  json.RawMessage   `json:"request,omitempty"`
+	// Timeout as accepted by /rpc e.g 5s or a number of seconds
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// savedRequest is a named call
+type savedRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	call
+	Updated time.Time `json:"updated"`
+}
+
+// historyEntry is a call made and what it returned
+type historyEntry struct {
+	ID string `json:"id"`
+	call
+	Status    int    `json:"status"`
+	Response  string `json:"response"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Latency in milliseconds
+	Latency int64 `json:"latency"`
+	// Node which served the call
+	Node string    `json:"node,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// accountKey is the store key prefix of the calls of the account making the request,
+// anonymous clients are told apart by a random id kept in a cookie
+func accountKey(prefix string, w http.ResponseWriter, r *http.Request) string {
+	var id string
+	if acc, ok := auth.AccountFromContext(r.Context()); ok {
+		id = acc.ID
+	} else {
+		id = "anonymous/" + clientID(w, r)
+	}
+	return prefix + serviceNamespace(r) + "/" + id + "/"
+}
+
+// clientID returns the id of an anonymous client, setting a new one if it has none
+func clientID(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(ClientCookieName); err == nil && validClientID(c.Value) {
+		return c.Value
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ClientCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int((time.Hour * 24 * 365).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// validClientID checks the id is one we'd set so it's safe to use in keys
+func validClientID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && strings.ToLower(id) == id
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// readAll returns the values of the keys with the prefix in key order
+func readAll(prefix string, v func([]byte) error) error {
+	keys, err := store.DefaultStore.List(store.ListPrefix(prefix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		recs, err := store.DefaultStore.Read(k)
+		if err != nil || len(recs) == 0 {
+			continue
+		}
+		if err := v(recs[0].Value); err != nil && logger.V(logger.DebugLevel, logger.DefaultLogger) {
+			logger.Debugf("Error decoding %s: %v", k, err)
+		}
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Error occurred:"+err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// SavedRequestsHandler lists, saves and deletes the named calls of an account
+func (s *srvWeb) SavedRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := accountKey(savedPrefix, w, r)
+
+	switch r.Method {
+	case "GET":
+		saved := []*savedRequest{}
+		err := readAll(prefix, func(b []byte) error {
+			var req *savedRequest
+			if err := json.Unmarshal(b, &req); err != nil {
+				return err
+			}
+			saved = append(saved, req)
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		sort.Slice(saved, func(i, j int) bool { return strings.ToLower(saved[i].Name) < strings.ToLower(saved[j].Name) })
+		writeJSON(w, map[string]interface{}{"requests": saved})
+	case "POST", "PUT":
+		var req *savedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if len(req.Name) == 0 || len(req.Service) == 0 || len(req.Endpoint) == 0 {
+			http.Error(w, "name, service and endpoint are required", http.StatusBadRequest)
+			return
+		}
+		if _, err := handler.ParseTimeout(req.Timeout); err != nil {
+			http.Error(w, "Invalid timeout: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// ids are ours to pick so they're safe to use in keys
+		if id := mux.Vars(r)["id"]; len(id) > 0 {
+			req.ID = id
+		} else {
+			req.ID = newID()
+		}
+		req.Updated = time.Now()
+
+		b, err := json.Marshal(req)
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		if err := store.DefaultStore.Write(&store.Record{Key: prefix + req.ID, Value: b}); err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		writeJSON(w, req)
+	case "DELETE":
+		id := mux.Vars(r)["id"]
+		if len(id) == 0 {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		if err := store.DefaultStore.Delete(prefix + id); err != nil && err != store.ErrNotFound {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HistoryHandler lists and records the last calls of an account
+func (s *srvWeb) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := accountKey(historyPrefix, w, r)
+
+	switch r.Method {
+	case "GET":
+		history := []*historyEntry{}
+		err := readAll(prefix, func(b []byte) error {
+			var e *historyEntry
+			if err := json.Unmarshal(b, &e); err != nil {
+				return err
+			}
+			history = append(history, e)
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		// newest first
+		for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+			history[i], history[j] = history[j], history[i]
+		}
+		writeJSON(w, map[string]interface{}{"history": history})
+	case "POST":
+		if ClientHistory <= 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var e *historyEntry
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil || e == nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		e.Time = time.Now()
+		// keys sort by time
+		e.ID = fmt.Sprintf("%020d-%s", e.Time.UnixNano(), newID())
+		if len(e.Response) > maxHistoryResponse {
+			e.Response = e.Response[:maxHistoryResponse]
+			e.Truncated = true
+		}
+
+		b, err := json.Marshal(e)
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		if err := store.DefaultStore.Write(&store.Record{Key: prefix + e.ID, Value: b}); err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+
+		trimHistory(prefix)
+		writeJSON(w, e)
+	case "DELETE":
+		keys, err := store.DefaultStore.List(store.ListPrefix(prefix))
+		if err != nil {
+			http.Error(w, "Error occurred:"+err.Error(), 500)
+			return
+		}
+		for _, k := range keys {
+			store.DefaultStore.Delete(k)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// trimHistory deletes the oldest calls beyond the history size
+func trimHistory(prefix string) {
+	keys, err := store.DefaultStore.List(store.ListPrefix(prefix))
+	if err != nil || len(keys) <= ClientHistory {
+		return
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys[:len(keys)-ClientHistory] {
+		store.DefaultStore.Delete(k)
+	}
+}
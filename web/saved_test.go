@@ -0,0 +1,170 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/micro/micro/v3/service/auth"
+	"github.com/micro/micro/v3/service/store"
+	"github.com/micro/micro/v3/service/store/memory"
+)
+
+// serve makes a request as the account to the handler
+func serve(h http.HandlerFunc, account, method, path, body string, vars map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if len(account) > 0 {
+		req = req.WithContext(auth.ContextWithAccount(req.Context(), &auth.Account{ID: account}))
+	}
+	if vars != nil {
+		req = mux.SetURLVars(req, vars)
+	}
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+func TestSavedRequests(t *testing.T) {
+	defer func(st store.Store) { store.DefaultStore = st }(store.DefaultStore)
+	store.DefaultStore = memory.NewStore()
+	s := &srvWeb{}
+
+	w := serve(s.SavedRequestsHandler, "alice", "POST", "/client/requests", `{"name": "hello", "service": "foo", "endpoint": "Foo.Call", "request": {"name": "John"}, "timeout": "1m30s"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 got %d %s", w.Code, w.Body.String())
+	}
+
+	var saved savedRequest
+	if err := json.Unmarshal(w.Body.Bytes(), &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.ID) == 0 || saved.Timeout != "1m30s" || string(saved.Request) != `{"name":"John"}` {
+		t.Fatalf("Unexpected saved request %+v", saved)
+	}
+
+	// requests missing what's needed to call aren't saved
+	if w := serve(s.SavedRequestsHandler, "alice", "POST", "/client/requests", `{"name": "bad"}`, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 got %d", w.Code)
+	}
+
+	// timeouts are those /rpc accepts
+	if w := serve(s.SavedRequestsHandler, "alice", "POST", "/client/requests", `{"name": "bad", "service": "foo", "endpoint": "Foo.Call", "timeout": "soon"}`, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 got %d", w.Code)
+	}
+
+	// update in place
+	serve(s.SavedRequestsHandler, "alice", "PUT", "/client/requests/"+saved.ID, `{"name": "renamed", "service": "foo", "endpoint": "Foo.Call"}`, map[string]string{"id": saved.ID})
+
+	list := func(account string) []*savedRequest {
+		var rsp struct {
+			Requests []*savedRequest `json:"requests"`
+		}
+		w := serve(s.SavedRequestsHandler, account, "GET", "/client/requests", "", nil)
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+		return rsp.Requests
+	}
+
+	if l := list("alice"); len(l) != 1 || l[0].Name != "renamed" {
+		t.Fatalf("Expected the renamed request got %+v", l)
+	}
+	// requests are kept per account
+	if l := list("bob"); len(l) != 0 {
+		t.Fatalf("Expected no requests for bob got %d", len(l))
+	}
+
+	serve(s.SavedRequestsHandler, "alice", "DELETE", "/client/requests/"+saved.ID, "", map[string]string{"id": saved.ID})
+	if l := list("alice"); len(l) != 0 {
+		t.Fatalf("Expected the request to be deleted got %d", len(l))
+	}
+}
+
+func TestHistory(t *testing.T) {
+	defer func(st store.Store) { store.DefaultStore = st }(store.DefaultStore)
+	store.DefaultStore = memory.NewStore()
+	s := &srvWeb{}
+
+	size := ClientHistory
+	ClientHistory = 2
+	defer func() { ClientHistory = size }()
+
+	for _, endpoint := range []string{"Foo.One", "Foo.Two", "Foo.Three"} {
+		w := serve(s.HistoryHandler, "alice", "POST", "/client/history", `{"service": "foo", "endpoint": "`+endpoint+`", "status": 200, "response": "{}", "latency": 12}`, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 got %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	var rsp struct {
+		History []*historyEntry `json:"history"`
+	}
+	w := serve(s.HistoryHandler, "alice", "GET", "/client/history", "", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	// the newest calls are kept, newest first
+	if len(rsp.History) != 2 || rsp.History[0].Endpoint != "Foo.Three" || rsp.History[1].Endpoint != "Foo.Two" {
+		t.Fatalf("Unexpected history %+v", rsp.History)
+	}
+	if rsp.History[0].Latency != 12 || rsp.History[0].Status != 200 {
+		t.Fatalf("Expected the status and latency to be kept got %+v", rsp.History[0])
+	}
+}
+
+func TestAnonymousClients(t *testing.T) {
+	defer func(st store.Store) { store.DefaultStore = st }(store.DefaultStore)
+	store.DefaultStore = memory.NewStore()
+	s := &srvWeb{}
+
+	// anonymous calls are kept by the client cookie
+	call := func(cookie *http.Cookie, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/client/requests", strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.SavedRequestsHandler(w, req)
+		return w
+	}
+
+	w := call(nil, "POST", `{"name": "hello", "service": "foo", "endpoint": "Foo.Call"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 got %d %s", w.Code, w.Body.String())
+	}
+
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == ClientCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("Expected an http only client cookie got %+v", cookie)
+	}
+
+	list := func(cookie *http.Cookie) []*savedRequest {
+		var rsp struct {
+			Requests []*savedRequest `json:"requests"`
+		}
+		if err := json.Unmarshal(call(cookie, "GET", "").Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+		return rsp.Requests
+	}
+
+	if l := list(cookie); len(l) != 1 || l[0].Name != "hello" {
+		t.Fatalf("Expected the saved request got %+v", l)
+	}
+	// other anonymous clients don't see it
+	if l := list(nil); len(l) != 0 {
+		t.Fatalf("Expected no requests for a new client got %d", len(l))
+	}
+	if l := list(&http.Cookie{Name: ClientCookieName, Value: "../" + cookie.Value}); len(l) != 0 {
+		t.Fatalf("Expected no requests for an invalid client id got %d", len(l))
+	}
+}
@@ -0,0 +1,42 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	utils "github.com/micro-community/micro-webui/helper/registry"
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// SchemaHandler serves the JSON Schemas of the request and response of an endpoint
+func (s *srvWeb) SchemaHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ns := namespace.FromContext(r.Context())
+
+	sv, err := s.registry.GetService(vars["name"], registry.GetContext(r.Context()), registry.GetDomain(ns))
+	if err != nil && err != registry.ErrNotFound {
+		http.Error(w, "Error occurred:"+err.Error(), 500)
+		return
+	}
+
+	ep := utils.FindEndpoint(sv, vars["endpoint"])
+	if ep == nil {
+		http.Error(w, "Not found", 404)
+		return
+	}
+
+	b, err := json.Marshal(map[string]interface{}{
+		"service":  vars["name"],
+		"endpoint": ep.Name,
+		"request":  utils.Schema(ep.Request),
+		"response": utils.Schema(ep.Response),
+	})
+	if err != nil {
+		http.Error(w, "Error occurred:"+err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/registry/memory"
+)
+
+func TestSchemaHandler(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(&registry.Service{
+		Name:    "foo",
+		Version: "v1",
+		Nodes:   []*registry.Node{testNode("foo-1")},
+		Endpoints: []*registry.Endpoint{{
+			Name:     "Foo.Call",
+			Request:  &registry.Value{Name: "Request", Type: "Request", Values: []*registry.Value{{Name: "name", Type: "string"}}},
+			Response: &registry.Value{Name: "Response", Type: "Response", Values: []*registry.Value{{Name: "msg", Type: "string"}}},
+		}},
+	})
+
+	s := &srvWeb{registry: reg}
+
+	testData := []struct {
+		name     string
+		endpoint string
+		code     int
+	}{
+		{"foo", "Foo.Call", http.StatusOK},
+		{"foo", "Foo.Missing", http.StatusNotFound},
+		{"bar", "Bar.Call", http.StatusNotFound},
+	}
+
+	for _, d := range testData {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/", nil), map[string]string{"name": d.name, "endpoint": d.endpoint})
+		w := httptest.NewRecorder()
+		s.SchemaHandler(w, req)
+
+		if w.Code != d.code {
+			t.Fatalf("Expected status %d for %s got %d", d.code, d.endpoint, w.Code)
+		}
+		if d.code != http.StatusOK {
+			continue
+		}
+
+		var rsp struct {
+			Request  map[string]interface{} `json:"request"`
+			Response map[string]interface{} `json:"response"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Request["$ref"] != "#/$defs/Request" || rsp.Response["$ref"] != "#/$defs/Response" {
+			t.Fatalf("Unexpected schemas %v", rsp)
+		}
+	}
+}
@@ -68,6 +68,8 @@ var (
 		  {{if gt (len .User) 0 }}<span class="user small">Logged in as: {{.User}}</span>{{end}}
 	          <li><a href="/client">Client</a></li>
 	          <li><a href="/services">Services</a></li>
+	          <li><a href="/routes">Routes</a></li>
+	          <li><a href="/openapi">API</a></li>
 	          {{if .StatsURL}}<li><a href="{{.StatsURL}}" class="navbar-link">Stats</a></li>{{end}}
 	          {{if .LoginURL}}<li><a href="{{.LoginURL}}" class="navbar-link">{{.LoginTitle}}</a></li>{{end}}
 	        </ul>
@@ -260,21 +262,59 @@ jQuery(function($, undefined) {
 				<ul class="list-group">
 					<input class="form-control" type=text name=metadata id=metadata placeholder="Metadata" value="{}"/>
 				</ul>
+				<div class="row">
+					<div class="col-sm-8">
+						<label for="address">Address</label>
+						<input class="form-control" type=text name=address id=address placeholder="Any node"/>
+					</div>
+					<div class="col-sm-4">
+						<label for="timeout">Timeout</label>
+						<input class="form-control" type=text name=timeout id=timeout placeholder="Default e.g 5s"/>
+					</div>
+				</div>
+				<br/>
 				<label for="request">Request</label>
 				<textarea class="form-control" name=request id=request rows=8>{}</textarea>
+				<p class="text-danger small" id="request-errors"></p>
 			</div>
 			<div class="form-group">
 				<button class="btn btn-default" style="border-color: whitesmoke;">Call</button>
+				<span class="pull-right">
+					<input type=hidden id=saved-id />
+					<input class="form-control input-sm" style="display: inline; width: auto;" type=text id=saved-name placeholder="Name"/>
+					<a href="#" class="btn btn-default btn-sm" style="border-color: whitesmoke;" onclick="return saveRequest();">Save</a>
+					<a href="#" class="btn btn-default btn-sm" style="border-color: whitesmoke;" onclick="return shareRequest();">Share</a>
+				</span>
 			</div>
 		</form>
 	</div>
 	<div class="col-sm-7">
-		<p><b>Response</b><span class="pull-right"><a href="#" onclick="copyResponse()">Copy</a></p>
+		<p><b>Response</b> <span class="small" id="latency"></span><span class="pull-right"><a href="#" onclick="return exportCall('curl')">curl</a> | <a href="#" onclick="return exportCall('micro')">micro call</a> | <a href="#" onclick="copyResponse()">Copy</a></span></p>
 		<pre id="response" style="min-height: 405px; max-height: 405px; overflow: scroll;">{}</pre>
 	</div>
     </div>
   </div>
 </div>
+<div class="row">
+	<div class="col-sm-5">
+		<h4>Saved</h4>
+		<table class="table table-condensed small" id="saved">
+			<tbody></tbody>
+		</table>
+	</div>
+	<div class="col-sm-7">
+		<h4>History <small><a href="#" onclick="return clearHistory();">clear</a></small></h4>
+		<table class="table table-condensed small" id="history">
+			<thead>
+				<th>Time</th>
+				<th>Call</th>
+				<th>Status</th>
+				<th>Latency</th>
+			</thead>
+			<tbody></tbody>
+		</table>
+	</div>
+</div>
 {{end}}
 {{define "script"}}
 	<script>
@@ -306,6 +346,7 @@ jQuery(function($, undefined) {
 				{{end}}
 				s_map[{{$service}}] = m_list
 				{{ end }}
+				schema = null;
 				if (select in s_map) {
 					var serviceEndpoints = s_map[select]
 					var len = serviceEndpoints.length;
@@ -328,16 +369,126 @@ jQuery(function($, undefined) {
 					$('#otherendpoint').val('');
 				}
 
+				// prefill the request unless the user has written their own
+				var ep = e_map[$("#service").val() + "/" + select];
+				schema = ep ? ep.schema : null;
+				var current = $("#request").val().trim();
+				if (current == "" || current == "{}" || current == example) {
+					example = ep ? ep.example : "{}";
+					$("#request").val(example);
+				}
+				validateRequest();
 			});
+
+			$("#request").on("input", validateRequest);
 		});
 	</script>
+	<script>
+		// the example request and schema of each endpoint by service/endpoint
+		var e_map = {};
+		{{ range $service, $endpoints := .Results }}
+		{{range $endpoints}}
+		e_map[{{$service}} + "/" + {{.Name}}] = {"example": {{example .Request}}, "schema": {{.Request}}};
+		{{end}}
+		{{ end }}
+
+		// the schema of the selected endpoint and the request it was prefilled with
+		var schema = null;
+		var example = "{}";
+
+		function isRepeated(type) {
+			return type.indexOf("[]") == 0 && type != "[]byte" && type != "[]uint8";
+		}
+
+		function isNumber(type) {
+			return /^(u?int(8|16|32|64)?|s?fixed(32|64)|sint(32|64)|float(32|64)?|double)$/.test(type);
+		}
+
+		// validate returns the fields of the value which don't match the schema
+		function validate(value, schema, path) {
+			if (value === null || schema == null) {
+				return [];
+			}
+
+			var type = schema.type || "";
+			var fields = schema.values || [];
+			var errors = [];
+
+			if (isRepeated(type)) {
+				if (!Array.isArray(value)) {
+					return [path + " should be a list"];
+				}
+				var elem = {"name": schema.name, "type": type.slice(2), "values": fields};
+				for (var i = 0; i < value.length; i++) {
+					errors = errors.concat(validate(value[i], elem, path + "[" + i + "]"));
+				}
+				return errors;
+			}
+
+			if (fields.length > 0) {
+				if (typeof value != "object" || Array.isArray(value)) {
+					return [path + " should be an object"];
+				}
+				for (var key in value) {
+					var field = fields.find(function(f) { return f.name.toLowerCase() == key.toLowerCase(); });
+					var name = path.length > 0 ? path + "." + key : key;
+					if (field == undefined) {
+						errors.push(name + " is not a field of " + (schema.type || "the request"));
+						continue;
+					}
+					errors = errors.concat(validate(value[key], field, name));
+				}
+				return errors;
+			}
+
+			if (type == "bool" && typeof value != "boolean") {
+				errors.push(path + " should be a boolean");
+			} else if (isNumber(type) && typeof value != "number" && !/^-?[0-9]+$/.test(value)) {
+				// 64 bit integers may be strings
+				errors.push(path + " should be a number");
+			} else if (type == "string" && typeof value != "string") {
+				errors.push(path + " should be a string");
+			}
+			return errors;
+		}
+
+		// validateRequest shows why the request doesn't match the schema, returning whether it does
+		function validateRequest() {
+			var errors = [];
+			var rq = $("#request").val();
+			if (rq.trim().length > 0) {
+				try {
+					errors = validate(JSON.parse(rq), schema, "");
+				} catch(e) {
+					errors = ["Invalid json: " + e.message];
+				}
+			}
+			$("#request-errors").text(errors.join(", "));
+			return errors.length == 0;
+		}
+	</script>
 	<script>
 		function call() {
+			if (!validateRequest()) {
+				return false;
+			}
+
+			var c = currentCall();
+			if (c == null) {
+				return false;
+			}
+
+			var start = Date.now();
 			var req = new XMLHttpRequest()
 			req.onreadystatechange = function() {
 				if(req.readyState != 4) {
 					return
 				}
+				// prefer the time the call took over the round trip
+				var latency = Math.round(parseFloat(req.getResponseHeader("X-Micro-Latency"))) || Date.now() - start;
+				var node = req.getResponseHeader("X-Micro-Node") || "";
+				showLatency(req.status, latency, node);
+				recordCall(c, req.status, req.responseText, latency, node);
 				if (req.readyState == 4 && req.status == 200) {
 					document.getElementById("response").innerText = JSON.stringify(JSON.parse(req.responseText), null, 2);
 				} else if (req.responseText.slice(0, 1) == "{") {
@@ -349,46 +500,254 @@ jQuery(function($, undefined) {
 				}
 				console.log(req.responseText);
 			}
+			req.open("POST", "/rpc", true);
+			req.setRequestHeader("Content-type","application/json");
+			req.send(JSON.stringify(rpcRequest(c)));
+
+			return false;
+		};
+
+		// rpcRequest is the body of a call to /rpc
+		function rpcRequest(c) {
+			var request = {
+				"service": c.service,
+				"endpoint": c.endpoint,
+				"request": c.request === undefined ? {} : c.request
+			};
+			if (c.address) {
+				request.address = c.address;
+			}
+			if (c.metadata && Object.keys(c.metadata).length > 0) {
+				request.metadata = c.metadata;
+			}
+			if (c.timeout) {
+				request.timeout = c.timeout;
+			}
+			return request;
+		}
+
+		function showLatency(status, latency, node) {
+			$("#latency").text(status + " in " + latency + "ms" + (node ? " from " + node : ""));
+		}
+
+		// currentCall returns the call described by the form or null if it's invalid
+		function currentCall() {
 			var endpoint = document.forms[0].elements["endpoint"].value
 			if (!($('#otherendpoint').prop('disabled'))) {
 				endpoint = document.forms[0].elements["otherendpoint"].value
 			}
 
-			var reqBody;
-			var headers;
+			var c = {
+				"service": document.forms[0].elements["service"].value,
+				"endpoint": endpoint,
+				"address": $("#address").val(),
+				"timeout": $("#timeout").val().trim()
+			};
 
 			try {
 				var md = document.forms[0].elements["metadata"].value;
 				var rq = document.forms[0].elements["request"].value
 				if (md.length > 0) {
-					headers = JSON.parse(md);
+					c.metadata = JSON.parse(md);
 				}
 				if (rq.length > 0) {
-					reqBody = JSON.parse(rq);
+					c.request = JSON.parse(rq);
 				};
 			} catch(e) {
 				document.getElementById("response").innerText = "Invalid request: " + e.message;
-				return false;
+				return null;
 			}
 
-			var request = {
-				"service": document.forms[0].elements["service"].value,
-				"endpoint": endpoint,
-				"request": reqBody
+			return c;
+		}
+
+		// loadCall fills the form with a saved, shared or previous call
+		function loadCall(c) {
+			$("#service").val(c.service).change();
+			if ($("#endpoint option[value='" + c.endpoint + "']").length > 0) {
+				$("#endpoint").val(c.endpoint);
+			} else {
+				$("#endpoint").val("other");
 			}
-			req.open("POST", "/rpc", true);
-			req.setRequestHeader("Content-type","application/json");
+			$("#endpoint").change();
+			$("#otherendpoint").val($("#endpoint").val() == "other" ? c.endpoint : "");
+			$("#metadata").val(JSON.stringify(c.metadata || {}));
+			$("#request").val(JSON.stringify(c.request === undefined ? {} : c.request, null, 2));
+			$("#address").val(c.address || "");
+			$("#timeout").val(c.timeout || "");
+			$("#saved-id").val(c.id || "");
+			$("#saved-name").val(c.name || "");
+			validateRequest();
+		}
 
-			if (headers != undefined) {
-				for (let [key, value] of Object.entries(headers)) {
-					req.setRequestHeader(key, value);
+		function send(method, url, body, done) {
+			var req = new XMLHttpRequest();
+			req.onreadystatechange = function() {
+				if (req.readyState != 4) {
+					return;
 				}
+				if (req.status >= 300) {
+					document.getElementById("response").innerText = req.responseText || "Request error " + req.status;
+					return;
+				}
+				if (done) {
+					done(req.responseText.length > 0 ? JSON.parse(req.responseText) : null);
+				}
+			};
+			req.open(method, url, true);
+			req.setRequestHeader("Content-type", "application/json");
+			req.send(body === undefined ? null : JSON.stringify(body));
+		}
+
+		var saved = [];
+
+		function loadSaved() {
+			send("GET", "/client/requests", undefined, function(rsp) {
+				saved = rsp.requests;
+				var body = $("#saved tbody").empty();
+				saved.forEach(function(c, i) {
+					var row = $("<tr>");
+					row.append($("<td>").append($("<a href='#'>").text(c.name).click(function() { loadCall(saved[i]); return false; })));
+					row.append($("<td>").text(c.service + " " + c.endpoint));
+					row.append($("<td class='text-right'>").append($("<a href='#'>").text("delete").click(function() { return deleteRequest(c.id); })));
+					body.append(row);
+				});
+			});
+		}
+
+		function saveRequest() {
+			var c = currentCall();
+			if (c == null) {
+				return false;
+			}
+			c.name = $("#saved-name").val();
+			if (c.name.length == 0) {
+				document.getElementById("response").innerText = "Name the request to save it";
+				return false;
 			}
 
-			req.send(JSON.stringify(request));
+			var id = $("#saved-id").val();
+			send(id ? "PUT" : "POST", "/client/requests" + (id ? "/" + id : ""), c, function(rsp) {
+				$("#saved-id").val(rsp.id);
+				loadSaved();
+			});
+			return false;
+		}
+
+		function deleteRequest(id) {
+			send("DELETE", "/client/requests/" + id, undefined, function() {
+				if ($("#saved-id").val() == id) {
+					$("#saved-id").val("");
+				}
+				loadSaved();
+			});
+			return false;
+		}
+
+		// shareRequest shows a link which opens the page with the call, it's kept in the fragment
+		function shareRequest() {
+			var c = currentCall();
+			if (c == null) {
+				return false;
+			}
+			c.name = $("#saved-name").val();
+			var data = btoa(unescape(encodeURIComponent(JSON.stringify(c))));
+			var url = location.origin + location.pathname + "#call=" + encodeURIComponent(data);
+			document.getElementById("response").innerText = url;
+			return false;
+		}
+
+		var calls = [];
+
+		function loadHistory() {
+			send("GET", "/client/history", undefined, function(rsp) {
+				calls = rsp.history;
+				var body = $("#history tbody").empty();
+				calls.forEach(function(h, i) {
+					var row = $("<tr>").addClass(h.status >= 200 && h.status < 300 ? "" : "danger");
+					row.append($("<td>").text(new Date(h.time).toLocaleTimeString()));
+					row.append($("<td>").append($("<a href='#'>").text(h.service + " " + h.endpoint).click(function() {
+						// a previous call isn't a saved request
+						loadCall(Object.assign({}, calls[i], {"id": "", "name": ""}));
+						showResponse(calls[i].response);
+						showLatency(calls[i].status, calls[i].latency, calls[i].node);
+						return false;
+					})));
+					row.append($("<td>").text(h.status));
+					row.append($("<td>").text(h.latency + "ms"));
+					body.append(row);
+				});
+			});
+		}
+
+		function recordCall(c, status, response, latency, node) {
+			var h = Object.assign({}, c, {"status": status, "response": response, "latency": latency, "node": node});
+			send("POST", "/client/history", h, loadHistory);
+		}
 
+		function clearHistory() {
+			send("DELETE", "/client/history", undefined, loadHistory);
 			return false;
-		};	
+		}
+
+		function showResponse(text) {
+			try {
+				text = JSON.stringify(JSON.parse(text), null, 2);
+			} catch(e) {}
+			document.getElementById("response").innerText = text;
+		}
+
+		function quote(s) {
+			return "'" + String(s).replace(/'/g, "'\\''") + "'";
+		}
+
+		// exportCall shows the command line making the call of the form
+		function exportCall(format) {
+			var c = currentCall();
+			if (c == null) {
+				return false;
+			}
+			var body = JSON.stringify(c.request === undefined ? {} : c.request);
+			var cmd = [];
+
+			if (format == "curl") {
+				cmd.push("curl -X POST -H 'Content-Type: application/json'");
+				cmd.push("-d " + quote(JSON.stringify(rpcRequest(c))));
+				cmd.push(quote(location.origin + "/rpc"));
+			} else {
+				cmd.push("micro call");
+				if (c.address) {
+					cmd.push("--address=" + quote(c.address));
+				}
+				for (let [key, value] of Object.entries(c.metadata || {})) {
+					cmd.push("--metadata=" + quote(key + "=" + value));
+				}
+				if (c.timeout) {
+					cmd.push("--request_timeout=" + quote(isNaN(c.timeout) ? c.timeout : c.timeout + "s"));
+				}
+				cmd.push(quote(c.service), quote(c.endpoint), quote(body));
+			}
+
+			document.getElementById("response").innerText = cmd.join(" ");
+			return false;
+		}
+
+		$(document).ready(function() {
+			loadSaved();
+			loadHistory();
+
+			// open a shared call
+			if (location.hash.indexOf("#call=") == 0) {
+				try {
+					var data = decodeURIComponent(location.hash.slice(6));
+					var c = JSON.parse(decodeURIComponent(escape(atob(data))));
+					delete c.id;
+					loadCall(c);
+				} catch(e) {
+					document.getElementById("response").innerText = "Invalid shared request: " + e.message;
+				}
+			}
+		});
 	</script>
 {{end}}
 `
@@ -423,7 +782,7 @@ jQuery(function($, undefined) {
 
 	serviceTemplate = `
 {{define "title"}}Service{{end}}
-{{define "heading"}}<h3>{{with $svc := index .Results 0}}{{$svc.Name}}{{end}}</h3>{{end}}
+{{define "heading"}}<h3>{{.Results.Name}} <small><a href="/service/{{.Results.Name}}/openapi">API</a></small></h3>{{end}}
 {{define "style"}}
 .table>tbody>tr>th, .table>tbody>tr>td {
     border-top: none;
@@ -434,27 +793,67 @@ jQuery(function($, undefined) {
 .bold {
   font-weight: bold;
 }
+.join { color: #3c763d; }
+.leave { color: #a94442; }
 pre {padding: 20px;}
 {{end}}
 {{define "script"}}
 <script type="text/javascript">
-  $('.endpoint').on('click', function() {
-	var val = $(this).parent().find("table");
-	var state = $(this).find(".state");
-	if (val.css('display') == 'none') {
+  function expand(el, open) {
+	var val = el.parent().find("table");
+	var state = el.find(".state");
+	if (open) {
 	  state.text("[-]");
 	  val.css('display', 'table');
 	} else {
 	  val.css('display', 'none');
 	  state.text("[+]");
 	}
+  }
+
+  $('.endpoint').on('click', function() {
+	expand($(this), $(this).parent().find("table").css('display') == 'none');
   });
+
+  $('#expand').on('click', function(e) {
+	e.preventDefault();
+	$('.endpoint').each(function() { expand($(this), true); });
+  });
+
+  $('#collapse').on('click', function(e) {
+	e.preventDefault();
+	$('.endpoint').each(function() { expand($(this), false); });
+  });
+
+  // append node changes as the registry reports them
+  if (window.EventSource) {
+	var source = new EventSource("/service/" + encodeURIComponent({{.Results.Name}}) + "/events");
+	var add = function(e) {
+	  var ev = JSON.parse(e.data);
+	  var row = $("<tr>").addClass(ev.action);
+	  row.append($("<td>").text(new Date(ev.time).toLocaleString()));
+	  row.append($("<td>").text(ev.action));
+	  row.append($("<td>").text(ev.version));
+	  row.append($("<td>").text(ev.node || ""));
+	  row.append($("<td>").text(ev.address || ""));
+	  $('#history tbody').append(row);
+	  $('#history').show();
+	  $('#no-history').hide();
+	  if (ev.action != "update") {
+	    $('#live').show();
+	  }
+	};
+	source.addEventListener("join", add);
+	source.addEventListener("leave", add);
+	source.addEventListener("update", add);
+  }
 </script>
 {{end}}
 {{define "content"}}
 	<hr>
 	<h4 class="bold">Nodes</h4>
-	{{range .Results}}
+	<p id="live" class="small" style="display: none;">Nodes have changed, <a href="">reload</a> to see them.</p>
+	{{range .Results.Services}}
 	<h5>Version: {{.Version}}</h5>
 	<table class="table">
 		<thead>
@@ -473,14 +872,67 @@ pre {padding: 20px;}
 		</tbody>
 	</table>
 	{{end}}
-	{{with $svc := index .Results 0}}
+	<h4 class="bold">History</h4>
+	<p id="no-history" class="small"{{if .Results.History}} style="display: none;"{{end}}>No nodes have joined or left since the dashboard started.</p>
+	<table id="history" class="table"{{if not .Results.History}} style="display: none;"{{end}}>
+		<thead>
+			<th>Time</th>
+			<th>Action</th>
+			<th>Version</th>
+			<th>Node</th>
+			<th>Address</th>
+		</thead>
+		<tbody>
+			{{range .Results.History}}
+			<tr class="{{.Action}}">
+				<td>{{.Time.Format "2006-01-02 15:04:05"}}</td>
+				<td>{{.Action}}</td>
+				<td>{{.Version}}</td>
+				<td>{{.Node}}</td>
+				<td>{{.Address}}</td>
+			</tr>
+			{{end}}
+		</tbody>
+	</table>
+	{{if .Results.Diffs}}
+	<h4 class="bold">Versions</h4>
+	{{range .Results.Diffs}}
+	<h5>{{.From}} &rarr; {{.To}}</h5>
+	<table class="table">
+		<tbody>
+			{{range .Metadata}}
+			<tr>
+				<th class="col-sm-2" scope="row">{{.Key}}</th>
+				<td><span class="leave">{{if .From}}{{.From}}{{else}}-{{end}}</span> &rarr; <span class="join">{{if .To}}{{.To}}{{else}}-{{end}}</span></td>
+			</tr>
+			{{end}}
+			{{if .Added}}
+			<tr>
+				<th class="col-sm-2" scope="row">Added</th>
+				<td class="join">{{range .Added}}{{.}} {{end}}</td>
+			</tr>
+			{{end}}
+			{{if .Removed}}
+			<tr>
+				<th class="col-sm-2" scope="row">Removed</th>
+				<td class="leave">{{range .Removed}}{{.}} {{end}}</td>
+			</tr>
+			{{end}}
+			{{if not (or .Metadata .Added .Removed)}}
+			<tr><td>No differences</td></tr>
+			{{end}}
+		</tbody>
+	</table>
+	{{end}}
+	{{end}}
+	{{with $svc := index .Results.Services 0}}
 	{{if $svc.Endpoints}}
-	<h4 class="bold">Endpoints</h4>
+	<h4 class="bold">Endpoints <small><a href="#" id="expand">expand all</a> | <a href="#" id="collapse">collapse all</a></small></h4>
 	<hr/>
 	{{end}}
 	{{range $svc.Endpoints}}
 	<div>
-		<h4 class="endpoint"><span class="state">[+]</span> {{.Name}}</h4>
+		<h4 class="endpoint"><span class="state">[+]</span> {{.Name}} <small><a href="/service/{{$svc.Name}}/schema/{{.Name}}">schema</a></small></h4>
 		<table class="table" style="display: none;">
 			<tbody>
 				{{if .Metadata}}
@@ -504,5 +956,160 @@ pre {padding: 20px;}
 	{{end}}
 {{end}}
 
+`
+
+	debugRouteTemplate = `
+{{define "title"}}Route{{end}}
+{{define "heading"}}<h3>Route</h3>{{end}}
+{{define "style"}}
+.table>tbody>tr>th, .table>tbody>tr>td {
+    border-top: none;
+}
+.form-control {
+	border: 1px solid whitesmoke;
+}
+.bold { font-weight: bold; }
+.matched { font-weight: bold; }
+.rejected { color: #999999; }
+{{end}}
+{{define "content"}}
+	<form class="form-inline" method="GET" action="/debug/route">
+		<div class="form-group">
+			<select class="form-control" name="method">
+			{{range .Results.Methods}}
+			<option value="{{.}}" {{if eq . $.Results.Method}}selected{{end}}>{{.}}</option>
+			{{end}}
+			</select>
+		</div>
+		<div class="form-group">
+			<input class="form-control" type=text name="host" placeholder="Host" value="{{.Results.Host}}"/>
+		</div>
+		<div class="form-group">
+			<input class="form-control" type=text name="path" placeholder="/greeter/say/hello" value="{{.Results.Path}}" size=40 autofocus/>
+		</div>
+		<button class="btn btn-default" style="border-color: whitesmoke;">Explain</button>
+	</form>
+	{{with .Results.Explanation}}
+	<hr>
+	{{if .Match}}
+	<h4 class="bold">Matched {{.Match.Service}} {{.Match.Endpoint}}{{if .Match.Layer}} in the {{.Match.Layer}} layer{{end}}</h4>
+	<p>Handler {{.Match.Handler}} via {{.Match.Pattern}}{{range $key, $value := .Match.Vars}} {{$key}}={{$value}}{{end}}</p>
+	{{else if .Fallback}}
+	<h4 class="bold">Resolved {{.Fallback.Service}} {{.Fallback.Endpoint}}</h4>
+	<p>No route matched, the {{.Fallback.Resolver}} resolver chose {{.Fallback.Service}}{{if .Fallback.Domain}} in {{.Fallback.Domain}}{{end}}{{if .Fallback.Handler}} with handler {{.Fallback.Handler}} and {{.Fallback.Nodes}} nodes{{end}}</p>
+	{{end}}
+	{{if .Error}}<p class="text-danger">{{.Error}}</p>{{end}}
+	<h4 class="bold">Candidates{{if .Domain}} in {{.Domain}}{{end}}</h4>
+	<table class="table">
+		<thead>
+			<th>Layer</th>
+			<th>Service</th>
+			<th>Endpoint</th>
+			<th>Method</th>
+			<th>Host</th>
+			<th>Path</th>
+			<th>Indexed</th>
+			<th>Result</th>
+		</thead>
+		<tbody>
+			{{range .Candidates}}
+			<tr class="{{if .Rejected}}rejected{{else}}matched{{end}}">
+				<td>{{.Layer}}</td>
+				<td>{{.Service}}</td>
+				<td>{{.Endpoint}}</td>
+				<td>{{range .Method}}{{.}} {{end}}</td>
+				<td>{{range .Host}}{{.}} {{end}}</td>
+				<td>{{range .Path}}{{.}} {{end}}</td>
+				<td>{{if .Indexed}}yes{{else}}no{{end}}</td>
+				<td>{{if .Rejected}}rejected by {{.Rejected}}{{else}}matched {{.Pattern}}{{range $key, $value := .Vars}} {{$key}}={{$value}}{{end}}{{end}}</td>
+			</tr>
+			{{end}}
+		</tbody>
+	</table>
+	{{end}}
+{{end}}
+`
+
+	routesTemplate = `
+{{define "title"}}Routes{{end}}
+{{define "heading"}}<h3>Routes</h3>{{end}}
+{{define "style"}}
+.table>tbody>tr>th, .table>tbody>tr>td {
+    border-top: none;
+}
+.form-control {
+	border: 1px solid whitesmoke;
+}
+{{end}}
+{{define "content"}}
+	<form class="form-inline" method="GET" action="/routes">
+		<div class="form-group">
+			<input class="form-control" type=text name="service" placeholder="Service" value="{{.Results.Service}}" autofocus/>
+		</div>
+		<div class="form-group">
+			<select class="form-control" name="method">
+			<option value="">Any method</option>
+			{{range .Results.Methods}}
+			<option value="{{.}}" {{if eq . $.Results.Method}}selected{{end}}>{{.}}</option>
+			{{end}}
+			</select>
+		</div>
+		<button class="btn btn-default" style="border-color: whitesmoke;">Filter</button>
+	</form>
+	<hr>
+	<table class="table">
+		<thead>
+			<th>Layer</th>
+			<th>Domain</th>
+			<th>Service</th>
+			<th>Versions</th>
+			<th>Endpoint</th>
+			<th>Handler</th>
+			<th>Method</th>
+			<th>Host</th>
+			<th>Templates</th>
+		</thead>
+		<tbody>
+			{{range .Results.Routes}}
+			<tr>
+				<td>{{.Layer}}</td>
+				<td>{{.Domain}}</td>
+				<td><a href="/service/{{.Service}}">{{.Service}}</a></td>
+				<td>{{range .Versions}}{{.}} {{end}}</td>
+				<td>{{.Endpoint.Name}}</td>
+				<td>{{.Endpoint.Handler}}</td>
+				<td>{{range .Endpoint.Method}}{{.}} {{end}}</td>
+				<td>{{range .Endpoint.Host}}{{.}} {{end}}</td>
+				<td>{{range .Templates}}<code>{{.}}</code> {{end}}</td>
+			</tr>
+			{{else}}
+			<tr><td colspan="9">No routes</td></tr>
+			{{end}}
+		</tbody>
+	</table>
+{{end}}
+`
+
+	openapiTemplate = `
+{{define "title"}}API{{end}}
+{{define "heading"}}<h3>{{if .Results.Name}}<a href="/service/{{.Results.Name}}">{{.Results.Name}}</a> API{{else}}API{{end}} <small><a href="{{.Results.URL}}">openapi.json</a></small></h3>{{end}}
+{{define "style"}}
+.swagger-ui .topbar { display: none; }
+.swagger-ui .info { margin: 20px 0; }
+{{end}}
+{{define "script"}}
+<script src="https://unpkg.com/swagger-ui-dist@3/swagger-ui-bundle.js"></script>
+<script type="text/javascript">
+  SwaggerUIBundle({
+	url: {{.Results.URL}},
+	dom_id: "#explorer",
+	deepLinking: true
+  });
+</script>
+{{end}}
+{{define "content"}}
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@3/swagger-ui.css">
+<div id="explorer"></div>
+{{end}}
 `
 )
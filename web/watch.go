@@ -0,0 +1,404 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/micro-community/micro-webui/namespace"
+	"github.com/micro/micro/v3/service/logger"
+	"github.com/micro/micro/v3/service/registry"
+)
+
+// Service events shown on the service page
+const (
+	eventJoin   = "join"
+	eventLeave  = "leave"
+	eventUpdate = "update"
+)
+
+var (
+	// historySize is the number of events kept per service
+	historySize = 100
+	// retryDelay is the wait before rewatching the registry
+	retryDelay = time.Second * 5
+)
+
+// serviceEvent is a change to a service
+type serviceEvent struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Service string    `json:"service"`
+	Version string    `json:"version"`
+	Node    string    `json:"node,omitempty"`
+	Address string    `json:"address,omitempty"`
+}
+
+// serviceWatcher records the node changes of services in every domain
+// and pushes them to the pages subscribed to the service
+type serviceWatcher struct {
+	registry registry.Registry
+	exit     chan bool
+
+	sync.Mutex
+	// versions last seen by domain and service name
+	seen    map[string][]*registry.Service
+	history map[string][]*serviceEvent
+	subs    map[string]map[chan *serviceEvent]bool
+}
+
+func newServiceWatcher(r registry.Registry) *serviceWatcher {
+	return &serviceWatcher{
+		registry: r,
+		exit:     make(chan bool),
+		seen:     make(map[string][]*registry.Service),
+		history:  make(map[string][]*serviceEvent),
+		subs:     make(map[string]map[chan *serviceEvent]bool),
+	}
+}
+
+func serviceKey(domain, name string) string {
+	return domain + "/" + name
+}
+
+// serviceDomain is the domain the registry reports a service in
+func serviceDomain(s *registry.Service) string {
+	if d := s.Metadata["domain"]; len(d) > 0 {
+		return d
+	}
+	for _, n := range s.Nodes {
+		if d := n.Metadata["domain"]; len(d) > 0 {
+			return d
+		}
+	}
+	return registry.DefaultDomain
+}
+
+// run watches the registry until stopped
+func (sw *serviceWatcher) run() {
+	for {
+		w, err := sw.registry.Watch(registry.WatchDomain(registry.WildcardDomain))
+		if err == nil {
+			// know what's running so only changes are recorded
+			sw.seed()
+			sw.watch(w)
+		} else if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("error watching services: %v", err)
+		}
+
+		select {
+		case <-sw.exit:
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (sw *serviceWatcher) watch(w registry.Watcher) {
+	done := make(chan bool)
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+		case <-sw.exit:
+		}
+		w.Stop()
+	}()
+
+	for {
+		res, err := w.Next()
+		if err != nil {
+			return
+		}
+		if res.Service == nil {
+			continue
+		}
+		// the event alone doesn't say which nodes remain
+		sw.refresh(serviceDomain(res.Service), res.Service.Name)
+	}
+}
+
+// seed records the running services without creating events
+func (sw *serviceWatcher) seed() {
+	services, err := sw.registry.ListServices(registry.ListDomain(registry.WildcardDomain))
+	if err != nil {
+		return
+	}
+
+	seen := make(map[string][]*registry.Service)
+	names := make(map[string]bool)
+
+	for _, s := range services {
+		if names[s.Name] {
+			continue
+		}
+		names[s.Name] = true
+
+		versions, err := sw.registry.GetService(s.Name, registry.GetDomain(registry.WildcardDomain))
+		if err != nil {
+			continue
+		}
+		for _, v := range versions {
+			k := serviceKey(serviceDomain(v), v.Name)
+			seen[k] = append(seen[k], v)
+		}
+	}
+
+	sw.Lock()
+	for k, versions := range seen {
+		if _, ok := sw.seen[k]; !ok {
+			sw.seen[k] = versions
+		}
+	}
+	sw.Unlock()
+}
+
+// refresh compares the service with what was last seen, recording the changes
+func (sw *serviceWatcher) refresh(domain, name string) {
+	versions, err := sw.registry.GetService(name, registry.GetDomain(domain))
+	if err != nil && err != registry.ErrNotFound {
+		return
+	}
+
+	sw.Lock()
+	defer sw.Unlock()
+
+	k := serviceKey(domain, name)
+	events := diffNodes(name, sw.seen[k], versions)
+	if len(versions) > 0 {
+		sw.seen[k] = versions
+	} else {
+		delete(sw.seen, k)
+	}
+
+	for _, ev := range events {
+		h := append(sw.history[k], ev)
+		if len(h) > historySize {
+			h = h[len(h)-historySize:]
+		}
+		sw.history[k] = h
+
+		for ch := range sw.subs[k] {
+			// drop events for pages which can't keep up
+			select {
+			case ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// events returns the recorded events of a service, oldest first
+func (sw *serviceWatcher) events(domain, name string) []*serviceEvent {
+	sw.Lock()
+	defer sw.Unlock()
+	return append([]*serviceEvent{}, sw.history[serviceKey(domain, name)]...)
+}
+
+// subscribe returns a channel of the events of a service and a function to stop them
+func (sw *serviceWatcher) subscribe(domain, name string) (<-chan *serviceEvent, func()) {
+	k := serviceKey(domain, name)
+	ch := make(chan *serviceEvent, 16)
+
+	sw.Lock()
+	if sw.subs[k] == nil {
+		sw.subs[k] = make(map[chan *serviceEvent]bool)
+	}
+	sw.subs[k][ch] = true
+	sw.Unlock()
+
+	return ch, func() {
+		sw.Lock()
+		delete(sw.subs[k], ch)
+		if len(sw.subs[k]) == 0 {
+			delete(sw.subs, k)
+		}
+		sw.Unlock()
+	}
+}
+
+func (sw *serviceWatcher) stop() {
+	select {
+	case <-sw.exit:
+	default:
+		close(sw.exit)
+	}
+}
+
+// diffNodes returns the nodes which joined or left and the versions whose metadata changed
+func diffNodes(name string, old, cur []*registry.Service) []*serviceEvent {
+	now := time.Now()
+
+	before := make(map[string]*registry.Service, len(old))
+	for _, s := range old {
+		before[s.Version] = s
+	}
+	after := make(map[string]*registry.Service, len(cur))
+	for _, s := range cur {
+		after[s.Version] = s
+	}
+
+	var events []*serviceEvent
+
+	for _, s := range cur {
+		prev := before[s.Version]
+		for _, n := range s.Nodes {
+			if prev == nil || !hasNode(prev.Nodes, n.Id) {
+				events = append(events, &serviceEvent{now, eventJoin, name, s.Version, n.Id, n.Address})
+			}
+		}
+		if prev != nil && len(diffMetadata(prev.Metadata, s.Metadata)) > 0 {
+			events = append(events, &serviceEvent{Time: now, Action: eventUpdate, Service: name, Version: s.Version})
+		}
+	}
+
+	for _, s := range old {
+		next := after[s.Version]
+		for _, n := range s.Nodes {
+			if next == nil || !hasNode(next.Nodes, n.Id) {
+				events = append(events, &serviceEvent{now, eventLeave, name, s.Version, n.Id, n.Address})
+			}
+		}
+	}
+
+	return events
+}
+
+func hasNode(nodes []*registry.Node, id string) bool {
+	for _, n := range nodes {
+		if n.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataChange is a metadata key which differs between versions
+type metadataChange struct {
+	Key  string `json:"key"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// versionDiff is the difference between two versions of a service
+type versionDiff struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Metadata []*metadataChange `json:"metadata,omitempty"`
+	// Added and Removed are endpoint names
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// diffMetadata returns the changed keys in order, ignoring the registry's domain key
+func diffMetadata(from, to map[string]string) []*metadataChange {
+	keys := make(map[string]bool)
+	for k := range from {
+		keys[k] = true
+	}
+	for k := range to {
+		keys[k] = true
+	}
+	delete(keys, "domain")
+
+	var changes []*metadataChange
+	for k := range keys {
+		if from[k] != to[k] {
+			changes = append(changes, &metadataChange{k, from[k], to[k]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+
+	return changes
+}
+
+// diffVersions compares each version with the one before it by version name
+func diffVersions(services []*registry.Service) []*versionDiff {
+	versions := append([]*registry.Service{}, services...)
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	var diffs []*versionDiff
+
+	for i := 1; i < len(versions); i++ {
+		a, b := versions[i-1], versions[i]
+		d := &versionDiff{
+			From:     a.Version,
+			To:       b.Version,
+			Metadata: diffMetadata(a.Metadata, b.Metadata),
+		}
+
+		eps := make(map[string]bool)
+		for _, ep := range a.Endpoints {
+			eps[ep.Name] = true
+		}
+		for _, ep := range b.Endpoints {
+			if !eps[ep.Name] {
+				d.Added = append(d.Added, ep.Name)
+			}
+			delete(eps, ep.Name)
+		}
+		for name := range eps {
+			d.Removed = append(d.Removed, name)
+		}
+		sort.Strings(d.Added)
+		sort.Strings(d.Removed)
+
+		diffs = append(diffs, d)
+	}
+
+	return diffs
+}
+
+// pingInterval keeps idle event streams open through proxies
+var pingInterval = time.Second * 30
+
+// ServiceEventsHandler streams the node changes of a service as server-sent events
+func (s *srvWeb) ServiceEventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusNotImplemented)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	events, stop := s.watcher.subscribe(serviceNamespace(r), name)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev := <-events:
+			b, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Action, b)
+		}
+		flusher.Flush()
+	}
+}
+
+// serviceNamespace is the registry domain of a dashboard request
+func serviceNamespace(r *http.Request) string {
+	if ns := namespace.FromContext(r.Context()); len(ns) > 0 {
+		return ns
+	}
+	return registry.DefaultDomain
+}
@@ -0,0 +1,160 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/micro/micro/v3/service/registry"
+	"github.com/micro/micro/v3/service/registry/memory"
+)
+
+func testNode(id string) *registry.Node {
+	return &registry.Node{Id: id, Address: "127.0.0.1:8080"}
+}
+
+func TestServiceWatcher(t *testing.T) {
+	reg := memory.NewRegistry()
+	sw := newServiceWatcher(reg)
+
+	foo := &registry.Service{Name: "foo", Version: "v1", Nodes: []*registry.Node{testNode("foo-1")}}
+	reg.Register(foo)
+	// known before watching so not a join
+	sw.seed()
+
+	events, stop := sw.subscribe(registry.DefaultDomain, "foo")
+	defer stop()
+
+	foo2 := &registry.Service{Name: "foo", Version: "v1", Nodes: []*registry.Node{testNode("foo-2")}}
+	reg.Register(foo2)
+	sw.refresh(registry.DefaultDomain, "foo")
+
+	reg.Deregister(foo)
+	sw.refresh(registry.DefaultDomain, "foo")
+
+	reg.Deregister(foo2)
+	sw.refresh(registry.DefaultDomain, "foo")
+
+	expect := []struct {
+		action string
+		node   string
+	}{
+		{eventJoin, "foo-2"},
+		{eventLeave, "foo-1"},
+		{eventLeave, "foo-2"},
+	}
+
+	history := sw.events(registry.DefaultDomain, "foo")
+	if len(history) != len(expect) {
+		t.Fatalf("Expected %d events got %d", len(expect), len(history))
+	}
+
+	for i, e := range expect {
+		if history[i].Action != e.action || history[i].Node != e.node {
+			t.Fatalf("Expected %s %s got %s %s", e.action, e.node, history[i].Action, history[i].Node)
+		}
+		if ev := <-events; ev != history[i] {
+			t.Fatalf("Expected event %d to be published", i)
+		}
+	}
+
+	// other domains are kept apart
+	if len(sw.events("tenant", "foo")) > 0 {
+		t.Fatal("Expected no events in the tenant domain")
+	}
+}
+
+func TestDiffNodes(t *testing.T) {
+	old := []*registry.Service{
+		{Name: "foo", Version: "v1", Metadata: map[string]string{"a": "1"}, Nodes: []*registry.Node{testNode("1")}},
+	}
+	cur := []*registry.Service{
+		{Name: "foo", Version: "v1", Metadata: map[string]string{"a": "2"}, Nodes: []*registry.Node{testNode("1")}},
+		{Name: "foo", Version: "v2", Nodes: []*registry.Node{testNode("2")}},
+	}
+
+	events := diffNodes("foo", old, cur)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events got %d", len(events))
+	}
+	if events[0].Action != eventUpdate || events[0].Version != "v1" {
+		t.Fatalf("Expected v1 update got %s %s", events[0].Action, events[0].Version)
+	}
+	if events[1].Action != eventJoin || events[1].Node != "2" {
+		t.Fatalf("Expected node 2 to join got %s %s", events[1].Action, events[1].Node)
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	services := []*registry.Service{
+		{
+			Name:      "foo",
+			Version:   "v2",
+			Metadata:  map[string]string{"domain": "micro", "owner": "bar", "region": "eu"},
+			Endpoints: []*registry.Endpoint{{Name: "Foo.Call"}, {Name: "Foo.Stream"}},
+		},
+		{
+			Name:      "foo",
+			Version:   "v1",
+			Metadata:  map[string]string{"domain": "micro", "owner": "foo"},
+			Endpoints: []*registry.Endpoint{{Name: "Foo.Call"}, {Name: "Foo.List"}},
+		},
+	}
+
+	diffs := diffVersions(services)
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 diff got %d", len(diffs))
+	}
+
+	d := diffs[0]
+	if d.From != "v1" || d.To != "v2" {
+		t.Fatalf("Expected v1 to v2 got %s to %s", d.From, d.To)
+	}
+	if len(d.Metadata) != 2 || d.Metadata[0].Key != "owner" || d.Metadata[1].Key != "region" {
+		t.Fatalf("Unexpected metadata changes %+v", d.Metadata)
+	}
+	if len(d.Added) != 1 || d.Added[0] != "Foo.Stream" {
+		t.Fatalf("Expected Foo.Stream added got %v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0] != "Foo.List" {
+		t.Fatalf("Expected Foo.List removed got %v", d.Removed)
+	}
+}
+
+func TestServicePage(t *testing.T) {
+	reg := memory.NewRegistry()
+	reg.Register(&registry.Service{Name: "foo", Version: "v1", Nodes: []*registry.Node{testNode("foo-1")}})
+	reg.Register(&registry.Service{Name: "foo", Version: "v2", Nodes: []*registry.Node{testNode("foo-2")}})
+
+	s := &srvWeb{registry: reg, watcher: newServiceWatcher(reg)}
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/service/foo", nil), map[string]string{"name": "foo"})
+	w := httptest.NewRecorder()
+	s.RegistryHandler(w, req)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "v1 &rarr; v2") {
+		t.Fatalf("Expected the service page got %d %s", w.Code, w.Body.String())
+	}
+
+	for _, header := range []string{"Accept", "Content-Type"} {
+		req := httptest.NewRequest("GET", "/service/foo", nil)
+		req.Header.Set(header, "application/json")
+		req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+
+		w := httptest.NewRecorder()
+		s.RegistryHandler(w, req)
+
+		var rsp struct {
+			Services []*registry.Service `json:"services"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatalf("Expected json for %s got %q", header, w.Body.String())
+		}
+		if len(rsp.Services) != 2 || rsp.Services[0].Name != "foo" {
+			t.Fatalf("Expected service foo got %+v", rsp.Services)
+		}
+	}
+}
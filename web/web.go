@@ -5,15 +5,29 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/micro-community/micro-webui/handler"
+	"github.com/micro-community/micro-webui/handler/cache"
 	"github.com/micro-community/micro-webui/handler/meta"
+	"github.com/micro-community/micro-webui/namespace"
 	"github.com/micro-community/micro-webui/resolver"
+	"github.com/micro-community/micro-webui/resolver/header"
+	"github.com/micro-community/micro-webui/resolver/host"
 	"github.com/micro-community/micro-webui/resolver/path"
+	"github.com/micro-community/micro-webui/resolver/pattern"
+	"github.com/micro-community/micro-webui/resolver/rpc"
+	"github.com/micro-community/micro-webui/resolver/subdomain"
+	"github.com/micro-community/micro-webui/resolver/vpath"
 	"github.com/micro-community/micro-webui/router"
+	"github.com/micro-community/micro-webui/router/chain"
 	regRouter "github.com/micro-community/micro-webui/router/registry"
+	"github.com/micro-community/micro-webui/router/static"
+	"github.com/micro-community/micro-webui/router/transform"
 	"github.com/micro-community/micro-webui/server"
+	"github.com/micro-community/micro-webui/server/compress"
 	"github.com/micro-community/micro-webui/server/httpweb"
 
 	"github.com/micro/micro/v3/service"
@@ -26,6 +40,8 @@ const (
 	BearerScheme = "Bearer "
 	// TokenCookieName is the name of the cookie which stores the auth token
 	TokenCookieName = "micro-token"
+	// ClientCookieName is the name of the cookie which identifies an anonymous client
+	ClientCookieName = "micro-client"
 )
 
 //Meta Fields of micro web
@@ -46,7 +62,7 @@ var (
 	// This is stripped from the request path
 	// Allows the web service to define absolute paths
 	APIPath               = "/{service:[a-zA-Z0-9]+}"
-	BasePathHeader        = "X-Micro-Web-Base-Path"
+	BasePathHeader        = transform.BasePathHeader
 	statsURL              string
 	loginURL              string
 	ACMEProvider          = "autocert"
@@ -54,6 +70,22 @@ var (
 
 	// Host name the web dashboard is served on
 	Host, _ = os.Hostname()
+	// Rewrite absolute links in html served by web apps
+	RewriteHTML = false
+	// Yaml or json file of static routes
+	RoutesFile string
+	// How often routes are fully resynced from the registry
+	ResyncInterval time.Duration
+	// Registry domains to route to, * for all
+	Domains []string
+	// Headers read by the header resolver
+	ServiceHeader  = header.DefaultServiceHeader
+	EndpointHeader = header.DefaultEndpointHeader
+	// Rules of the pattern resolver, loaded from ResolverRulesFile
+	ResolverRulesFile string
+	ResolverRules     []*pattern.Rule
+	// Number of calls kept in the client page history of each account
+	ClientHistory = 50
 )
 
 type srvWeb struct {
@@ -61,8 +93,47 @@ type srvWeb struct {
 	api      server.Server
 	rr       resolver.Resolver
 	rt       router.Router
+	cache    *cache.Cache
 	registry registry.Registry
-	logged   bool
+	// resolves the tenant namespace of dashboard requests
+	nr *namespace.Resolver
+	// records node changes for the service pages
+	watcher *serviceWatcher
+	logged  bool
+}
+
+// newResolver returns the resolver chosen by name, falling back to path
+func newResolver() resolver.Resolver {
+	opts := []resolver.Option{resolver.WithServicePrefix(Namespace), resolver.WithHandler(Handler)}
+
+	var rr resolver.Resolver = path.NewResolver(opts...)
+
+	switch Resolver {
+	case "host":
+		rr = host.NewResolver(opts...)
+	case "vpath":
+		rr = vpath.NewResolver(opts...)
+	case "rpc":
+		rr = rpc.NewResolver(opts...)
+	case "header":
+		rr = header.NewResolver(rr, header.ResolverOptions(opts...), header.ServiceHeader(ServiceHeader), header.EndpointHeader(EndpointHeader))
+	case "pattern":
+		// the rules were validated when loaded
+		pr, err := pattern.NewResolver(rr, ResolverRules, opts...)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		rr = pr
+	case "subdomain":
+		return subdomain.NewResolver(rr, opts...)
+	}
+
+	// route each subdomain to the services in its registry domain
+	if len(Domains) > 0 {
+		rr = subdomain.NewResolver(rr, opts...)
+	}
+
+	return rr
 }
 
 func New(address string, service *service.Service) *srvWeb {
@@ -71,14 +142,31 @@ func New(address string, service *service.Service) *srvWeb {
 		address = service.Server().Options().Address
 	}
 
-	rr := path.NewResolver(resolver.WithServicePrefix(Namespace), resolver.WithHandler(Handler))
-	rt := regRouter.NewRouter(router.WithResolver(rr), router.WithRegistry(registry.DefaultRegistry))
+	rr := newResolver()
+	// static routes override those discovered in the registry
+	rt := chain.NewRouter(
+		chain.Layer{
+			Name:   "static",
+			Router: static.NewRouter(router.WithResolver(rr), router.WithRegistry(registry.DefaultRegistry), router.WithRoutesFile(RoutesFile)),
+		},
+		chain.Layer{
+			Name:   "registry",
+			Router: regRouter.NewRouter(router.WithResolver(rr), router.WithRegistry(registry.DefaultRegistry), router.WithResyncInterval(ResyncInterval), router.WithDomains(Domains...)),
+		},
+	)
 
 	return &srvWeb{
-		api: httpweb.NewServer(address, server.EnableCORS(true)),
-		rr:  rr,
-		rt:  rt,
-		svc: service,
+		api: httpweb.NewServer(address,
+			server.EnableCORS(true),
+			server.WrapHandler(compress.NewWrapper()),
+		),
+		rr:       rr,
+		rt:       rt,
+		cache:    cache.New(),
+		svc:      service,
+		registry: registry.DefaultRegistry,
+		nr:       namespace.NewResolver(Type, Namespace),
+		watcher:  newServiceWatcher(registry.DefaultRegistry),
 	}
 
 }
@@ -110,20 +198,42 @@ func (s *srvWeb) Run() error {
 		return
 	})
 
-	r.HandleFunc("/client", s.CallHandler)
-	r.HandleFunc("/services", s.RegistryHandler)
-	r.HandleFunc("/service/{name}", s.RegistryHandler)
+	r.HandleFunc("/client", s.withTenant(s.CallHandler))
+	r.HandleFunc("/client/requests", s.withTenant(s.SavedRequestsHandler))
+	r.HandleFunc("/client/requests/{id:[a-f0-9]+}", s.withTenant(s.SavedRequestsHandler))
+	r.HandleFunc("/client/history", s.withTenant(s.HistoryHandler))
+	r.HandleFunc("/services", s.withTenant(s.RegistryHandler))
+	r.HandleFunc("/service/{name}", s.withTenant(s.RegistryHandler))
+	r.HandleFunc("/service/{name}/events", s.withTenant(s.ServiceEventsHandler))
+	r.HandleFunc("/service/{name}/openapi", s.withTenant(s.OpenAPIExplorerHandler))
+	r.HandleFunc("/service/{name}/openapi.json", s.withTenant(s.OpenAPIHandler))
+	r.HandleFunc("/service/{name}/schema/{endpoint}", s.withTenant(s.SchemaHandler))
+	r.HandleFunc("/openapi", s.withTenant(s.OpenAPIExplorerHandler))
+	r.HandleFunc("/openapi.json", s.withTenant(s.OpenAPIHandler))
+	r.Handle("/rpc/batch", s.withTenant(handler.NewBatchHandler(s.rr).ServeHTTP))
+	r.Handle("/rpc", s.withTenant(handler.NewRPCHandler(s.rr).ServeHTTP))
+	r.HandleFunc("/routes", s.withTenant(s.RoutesHandler))
+	r.HandleFunc("/cache/purge", s.withTenant(s.cache.PurgeHandler().ServeHTTP))
+	r.HandleFunc("/debug/route", s.withTenant(s.DebugRouteHandler))
+	r.HandleFunc("/debug/router", s.withTenant(s.RouterStatsHandler))
 	//r.PathPrefix("/{service:[a-zA-Z0-9]+}").Handler(p)
 
-	r.PathPrefix(APIPath).Handler(meta.NewMetaHandler(s.svc.Client(), s.rt, Namespace))
+	r.PathPrefix(APIPath).Handler(meta.NewMetaHandler(s.svc.Client(), s.rt, Namespace,
+		handler.WithCache(s.cache),
+		handler.WithStripPrefix(true),
+		handler.WithRewriteHTML(RewriteHTML),
+	))
 
 	// register the handler
 	s.api.Handle("/", h)
 
+	go s.watcher.run()
+
 	// Start API
 	return s.api.Start()
 }
 
 func (s *srvWeb) Stop() error {
+	s.watcher.stop()
 	return s.api.Stop()
 }